@@ -0,0 +1,259 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// AdvisoryLockInterval interval of checking whether the advisory lock is still held by its pinned connection
+var AdvisoryLockInterval = time.Second * 5
+
+// ErrLockTimeout the advisory lock is held by others and could not be acquired within the timeout
+var ErrLockTimeout = errors.New("advisory lock timeout")
+
+// ErrLockLost the advisory lock is not held anymore, the pinned connection died or the lock was released by others
+var ErrLockLost = errors.New("advisory lock lost")
+
+// AdvisoryLock user-level lock acquired by GET_LOCK, the lock belongs to the session, so a dedicated connection is pinned until it is released
+type AdvisoryLock struct {
+	name     string        // lock name
+	db       string        // name of the registered database connection
+	conn     *sql.Conn     // the connection the lock is pinned to
+	mutex    sync.Mutex    // statements on the pinned connection are executed one by one
+	released bool          // released or lost
+	err      error         // reason of losing the lock
+	lost     chan struct{} // closed when the lock is lost
+	stop     chan struct{} // closed when the lock is released
+}
+
+// lockTimeout the timeout of GET_LOCK in seconds, negative means waiting forever
+func lockTimeout(timeout time.Duration) int64 {
+	if timeout < 0 {
+		return -1
+	}
+	return int64(math.Ceil(timeout.Seconds()))
+}
+
+// discard close the connection without returning it to the pool, the locks held by its session are released by the server
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+	_ = conn.Close()
+}
+
+// GetLock acquire the advisory lock named name within timeout on a dedicated connection, negative timeout means waiting forever
+// it returns ErrLockTimeout if the lock is held by others until timeout
+func (s *Hat) GetLock(ctx context.Context, name string, timeout time.Duration) (*AdvisoryLock, error) {
+	if s.db == nil {
+		return nil, s.unregistered()
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?);", name, lockTimeout(timeout)).Scan(&acquired)
+	observe(s.name, KindQuery, err)
+	if err != nil {
+		discard(conn)
+		return nil, err
+	}
+	if !acquired.Valid {
+		discard(conn)
+		return nil, errors.New("advisory lock error")
+	}
+	if acquired.Int64 != 1 {
+		_ = conn.Close()
+		return nil, ErrLockTimeout
+	}
+	lock := &AdvisoryLock{
+		name: name,
+		db:   s.name,
+		conn: conn,
+		lost: make(chan struct{}),
+		stop: make(chan struct{}),
+	}
+	go lock.watch()
+	return lock, nil
+}
+
+// Name lock name
+func (s *AdvisoryLock) Name() string {
+	return s.name
+}
+
+// Lost closed when the lock is lost, such as the pinned connection died
+func (s *AdvisoryLock) Lost() <-chan struct{} {
+	return s.lost
+}
+
+// Err reason of losing the lock, nil if the lock is not lost
+func (s *AdvisoryLock) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// watch check whether the lock is still held by the pinned connection until it is released
+func (s *AdvisoryLock) watch() {
+	ticker := time.NewTicker(AdvisoryLockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if !s.check() {
+				return
+			}
+		}
+	}
+}
+
+// check the lock is still held, the lock is marked as lost if it is not
+func (s *AdvisoryLock) check() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.released {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), AdvisoryLockInterval)
+	defer cancel()
+	var held sql.NullInt64
+	err := s.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID();", s.name).Scan(&held)
+	observe(s.db, KindQuery, err)
+	if err == nil && held.Valid && held.Int64 == 1 {
+		return true
+	}
+	if err == nil {
+		err = ErrLockLost
+	}
+	s.lose(err)
+	return false
+}
+
+// lose mark the lock as lost and discard the pinned connection
+func (s *AdvisoryLock) lose(err error) {
+	s.released = true
+	s.err = err
+	close(s.lost)
+	discard(s.conn)
+}
+
+// Release release the lock and the pinned connection, it returns ErrLockLost if the lock was lost before
+func (s *AdvisoryLock) Release(ctx context.Context) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.released {
+		if s.err != nil {
+			err = ErrLockLost
+		}
+		return
+	}
+	s.released = true
+	close(s.stop)
+	var released sql.NullInt64
+	err = s.conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?);", s.name).Scan(&released)
+	observe(s.db, KindQuery, err)
+	if err != nil {
+		// the lock may still be held by the session, closing the connection releases it
+		discard(s.conn)
+		return
+	}
+	_ = s.conn.Close()
+	// 0: the lock is held by others, NULL: the lock does not exist
+	if !released.Valid || released.Int64 != 1 {
+		err = ErrLockLost
+	}
+	return
+}
+
+// WithLock execute closure while holding the advisory lock named name, the lock is released after closure returns
+// the context of closure is canceled if the lock is lost, and ErrLockLost is returned if closure does not return an error
+func (s *Hat) WithLock(ctx context.Context, name string, timeout time.Duration, closure func(ctx context.Context) (err error)) (err error) {
+	lock, err := s.GetLock(ctx, name, timeout)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	defer func() {
+		release := lock.Release(context.Background())
+		if err == nil {
+			err = release
+		}
+	}()
+	err = closure(ctx)
+	return
+}
+
+// IsUsedLock get the connection id of the session holding the advisory lock named name, 0 if the lock is free
+func (s *Hat) IsUsedLock(ctx context.Context, name string) (id int64, err error) {
+	if s.db == nil {
+		err = s.unregistered()
+		return
+	}
+	var used sql.NullInt64
+	err = s.db.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?);", name).Scan(&used)
+	observe(s.name, KindQuery, err)
+	id = used.Int64
+	return
+}
+
+// GetLock acquire the advisory lock named name within timeout on a dedicated connection
+func (s *Curd) GetLock(ctx context.Context, name string, timeout time.Duration) (*AdvisoryLock, error) {
+	return s.hat.GetLock(ctx, name, timeout)
+}
+
+// WithLock execute closure while holding the advisory lock named name
+func (s *Curd) WithLock(ctx context.Context, name string, timeout time.Duration, closure func(ctx context.Context) (err error)) error {
+	return s.hat.WithLock(ctx, name, timeout, closure)
+}
+
+// IsUsedLock get the connection id of the session holding the advisory lock named name, 0 if the lock is free
+func (s *Curd) IsUsedLock(ctx context.Context, name string) (int64, error) {
+	return s.hat.IsUsedLock(ctx, name)
+}
+
+// GetLock acquire the advisory lock named name within timeout on a dedicated connection
+func (s *Client) GetLock(ctx context.Context, name string, timeout time.Duration) (*AdvisoryLock, error) {
+	return s.Hat().GetLock(ctx, name, timeout)
+}
+
+// WithLock execute closure while holding the advisory lock named name
+func (s *Client) WithLock(ctx context.Context, name string, timeout time.Duration, closure func(ctx context.Context) (err error)) error {
+	return s.Hat().WithLock(ctx, name, timeout, closure)
+}
+
+// IsUsedLock get the connection id of the session holding the advisory lock named name, 0 if the lock is free
+func (s *Client) IsUsedLock(ctx context.Context, name string) (int64, error) {
+	return s.Hat().IsUsedLock(ctx, name)
+}
+
+// GetLock acquire the advisory lock named name within timeout on a dedicated connection
+func GetLock(ctx context.Context, name string, timeout time.Duration) (*AdvisoryLock, error) {
+	return Use(DefaultName).GetLock(ctx, name, timeout)
+}
+
+// WithLock execute closure while holding the advisory lock named name
+func WithLock(ctx context.Context, name string, timeout time.Duration, closure func(ctx context.Context) (err error)) error {
+	return Use(DefaultName).WithLock(ctx, name, timeout, closure)
+}
+
+// IsUsedLock get the connection id of the session holding the advisory lock named name, 0 if the lock is free
+func IsUsedLock(ctx context.Context, name string) (int64, error) {
+	return Use(DefaultName).IsUsedLock(ctx, name)
+}
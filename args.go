@@ -0,0 +1,293 @@
+package gomysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// quoted get the index after the string literal or quoted identifier starting at i
+func quoted(prepare string, i int) int {
+	quote := prepare[i]
+	length := len(prepare)
+	for j := i + 1; j < length; j++ {
+		if quote != '`' && prepare[j] == '\\' {
+			j++
+			continue
+		}
+		if prepare[j] != quote {
+			continue
+		}
+		// the quote is escaped by doubling it
+		if j+1 < length && prepare[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return length
+}
+
+// outside call visit with the index of every byte of prepare outside string literals, quoted identifiers and comments
+// visit returns the number of bytes it consumed, the next byte is visited if it returns 0
+func outside(prepare string, visit func(i int) int) {
+	scan(prepare, func(start int, end int, comment bool) {}, visit)
+}
+
+// scan call visit like outside, and call skip with the range of every string literal, quoted identifier and comment skipped
+func scan(prepare string, skip func(start int, end int, comment bool), visit func(i int) int) {
+	length := len(prepare)
+	for i := 0; i < length; {
+		switch {
+		case prepare[i] == '\'' || prepare[i] == '"' || prepare[i] == '`':
+			end := quoted(prepare, i)
+			skip(i, end, false)
+			i = end
+		case prepare[i] == '#' || strings.HasPrefix(prepare[i:], "--") && (i+2 == length || prepare[i+2] <= ' '):
+			end := strings.IndexByte(prepare[i:], '\n')
+			if end < 0 {
+				skip(i, length, true)
+				return
+			}
+			skip(i, i+end+1, true)
+			i += end + 1
+		case strings.HasPrefix(prepare[i:], "/*"):
+			end := strings.Index(prepare[i+2:], "*/")
+			if end < 0 {
+				skip(i, length, true)
+				return
+			}
+			skip(i, i+end+4, true)
+			i += end + 4
+		default:
+			consumed := visit(i)
+			if consumed <= 0 {
+				consumed = 1
+			}
+			i += consumed
+		}
+	}
+}
+
+// expandable the parameter is a slice or an array to be expanded, []byte and driver.Valuer are single parameters
+func expandable(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	value := reflect.ValueOf(arg)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return value.Type().Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// emptyList the replacement of the placeholder of an empty slice following prepare
+// an empty subquery after IN ( and NOT IN (, so IN is always false and NOT IN is always true like In and NotIn, otherwise NULL
+func emptyList(prepare string) string {
+	prepare = strings.TrimRight(prepare, " \t\r\n")
+	if !strings.HasSuffix(prepare, "(") {
+		return "NULL"
+	}
+	prepare = strings.ToUpper(strings.TrimRight(strings.TrimSuffix(prepare, "("), " \t\r\n"))
+	if !strings.HasSuffix(prepare, "IN") || len(prepare) > 2 && word(prepare[len(prepare)-3]) {
+		return "NULL"
+	}
+	return "SELECT NULL FROM DUAL WHERE 1 = 0"
+}
+
+// ExpandArgs expand the slice parameter bound to a single placeholder into placeholders of its elements
+// such as `id` IN ( ? ) with []int64{1, 2, 3} => `id` IN ( ?, ?, ? ) with 1, 2, 3, []byte and driver.Valuer are not expanded
+// the placeholder of an empty slice after IN ( or NOT IN ( is replaced by an empty subquery, so `id` IN ( ? ) is always false and `id` NOT IN ( ? ) is always true
+// the placeholder of an empty slice elsewhere is replaced by NULL
+func ExpandArgs(prepare string, args []interface{}) (string, []interface{}) {
+	expand := false
+	for _, arg := range args {
+		if expandable(arg) {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return prepare, args
+	}
+	var builder strings.Builder
+	result := make([]interface{}, 0, len(args))
+	last, index := 0, 0
+	outside(prepare, func(i int) int {
+		if prepare[i] != '?' || index >= len(args) {
+			return 1
+		}
+		arg := args[index]
+		index++
+		if !expandable(arg) {
+			result = append(result, arg)
+			return 1
+		}
+		builder.WriteString(prepare[last:i])
+		last = i + 1
+		value := reflect.ValueOf(arg)
+		length := value.Len()
+		if length == 0 {
+			builder.WriteString(emptyList(prepare[:i]))
+			return 1
+		}
+		builder.WriteString(strings.TrimSuffix(strings.Repeat("?, ", length), ", "))
+		for j := 0; j < length; j++ {
+			result = append(result, value.Index(j).Interface())
+		}
+		return 1
+	})
+	builder.WriteString(prepare[last:])
+	result = append(result, args[index:]...)
+	return builder.String(), result
+}
+
+// word the byte can be part of the name of a named parameter
+func word(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// bindable the parameter binds named parameters, a map[string]interface{}, a struct or a pointer to struct, except time.Time and driver.Valuer
+func bindable(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	if _, ok := arg.(map[string]interface{}); ok {
+		return true
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	tp := reflect.TypeOf(arg)
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	return tp.Kind() == reflect.Struct && tp != reflect.TypeOf(time.Time{})
+}
+
+// fields collect the exported fields of struct value named by their json tags into named, the value is bound as it is
+// fields tagged json:"-" are skipped, the fields of embedded structs are collected unless the name is used by an outer field
+func fields(value reflect.Value, named map[string]interface{}) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	tp := value.Type()
+	var embedded []reflect.Value
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		kind := field.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = field.Type.Elem().Kind()
+		}
+		if field.Anonymous && name == "" && kind == reflect.Struct {
+			embedded = append(embedded, value.Field(i))
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		named[name] = value.Field(i).Interface()
+	}
+	for _, tmp := range embedded {
+		outer := map[string]interface{}{}
+		fields(tmp, outer)
+		for name, field := range outer {
+			if _, ok := named[name]; !ok {
+				named[name] = field
+			}
+		}
+	}
+}
+
+// NamedArgs bind the named parameters :name and @name of prepare, args must be a map[string]interface{} or a struct as the only parameter
+// the fields of struct are named by their json tags or their names, and bound as they are, such as time.Time and []byte
+// such as `id` = :id with map[string]interface{}{"id": 1} => `id` = ? with 1
+// names inside string literals and comments, :: and @@ such as @@session.time_zone are ignored, @name not bound is kept as a user variable
+// prepare and args are unchanged if args is not a single map or struct, mixing named parameters with positional parameters ? is an error
+func NamedArgs(prepare string, args []interface{}) (string, []interface{}, error) {
+	if len(args) != 1 || !bindable(args[0]) {
+		return prepare, args, nil
+	}
+	named, ok := args[0].(map[string]interface{})
+	if !ok {
+		named = map[string]interface{}{}
+		fields(reflect.ValueOf(args[0]), named)
+	}
+	var err error
+	var builder strings.Builder
+	var result []interface{}
+	last, positional := 0, false
+	length := len(prepare)
+	outside(prepare, func(i int) int {
+		if err != nil {
+			return length
+		}
+		sign := prepare[i]
+		if sign == '?' {
+			positional = true
+		}
+		if sign != ':' && sign != '@' {
+			return 1
+		}
+		j := i + 1
+		// :: and @@ are not named parameters, skip the name following them too
+		if j < length && prepare[j] == sign {
+			j++
+			for j < length && word(prepare[j]) {
+				j++
+			}
+			return j - i
+		}
+		for j < length && word(prepare[j]) {
+			j++
+		}
+		if j == i+1 {
+			return 1
+		}
+		name := prepare[i+1 : j]
+		value, ok := named[name]
+		if !ok && sign == '@' {
+			// user variable of the session
+			return j - i
+		}
+		if !ok {
+			err = fmt.Errorf("named parameter %c%s is not bound", sign, name)
+			return length
+		}
+		builder.WriteString(prepare[last:i])
+		builder.WriteString("?")
+		last = j
+		result = append(result, value)
+		return j - i
+	})
+	if err != nil {
+		return prepare, args, err
+	}
+	if last == 0 {
+		return prepare, args, nil
+	}
+	// the positional parameters have no values when the only parameter binds the named parameters
+	if positional {
+		return prepare, args, errors.New("named parameters can not be mixed with positional parameters ?")
+	}
+	builder.WriteString(prepare[last:])
+	return builder.String(), result, nil
+}
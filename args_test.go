@@ -0,0 +1,213 @@
+package gomysql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type namedBase struct {
+	Id      int64  `json:"id"`
+	Deleted string `json:"deleted"`
+}
+
+type namedUser struct {
+	namedBase
+	Name     string    `json:"name"`
+	Password string    `json:"-"`
+	Created  time.Time `json:"created_at,omitempty"`
+	Avatar   []byte    `json:"avatar"`
+	Deleted  string    `json:"deleted"`
+	Status   int
+	secret   string
+}
+
+func TestNamedArgs(t *testing.T) {
+	created := time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC)
+	user := namedUser{
+		namedBase: namedBase{Id: 9007199254740993, Deleted: "base"},
+		Name:      "jack",
+		Password:  "secret",
+		Created:   created,
+		Avatar:    []byte{0, 1, 2},
+		Deleted:   "user",
+		Status:    1,
+		secret:    "secret",
+	}
+	tests := []struct {
+		name    string
+		prepare string
+		arg     interface{}
+		want    string
+		args    []interface{}
+	}{
+		{
+			name:    "struct",
+			prepare: "UPDATE `user` SET `name` = :name, `avatar` = :avatar, `created_at` = :created_at, `status` = :Status WHERE ( `id` = :id AND `deleted` = :deleted );",
+			arg:     user,
+			want:    "UPDATE `user` SET `name` = ?, `avatar` = ?, `created_at` = ?, `status` = ? WHERE ( `id` = ? AND `deleted` = ? );",
+			args:    []interface{}{"jack", []byte{0, 1, 2}, created, 1, int64(9007199254740993), "user"},
+		},
+		{
+			name:    "pointer to struct",
+			prepare: "SELECT * FROM `user` WHERE ( `id` = :id );",
+			arg:     &user,
+			want:    "SELECT * FROM `user` WHERE ( `id` = ? );",
+			args:    []interface{}{int64(9007199254740993)},
+		},
+		{
+			name:    "map",
+			prepare: "SELECT * FROM `user` WHERE ( `id` = :id OR `parent_id` = :id ) AND `name` = @name;",
+			arg:     map[string]interface{}{"id": 1, "name": "jack"},
+			want:    "SELECT * FROM `user` WHERE ( `id` = ? OR `parent_id` = ? ) AND `name` = ?;",
+			args:    []interface{}{1, 1, "jack"},
+		},
+		{
+			name:    "literals and comments",
+			prepare: "SELECT ':id', \":id\", `:id` /* :id */ FROM `user` WHERE `id` = :id -- :id\n AND `name` <> 'it''s :name' # :name\n;",
+			arg:     map[string]interface{}{"id": 1},
+			want:    "SELECT ':id', \":id\", `:id` /* :id */ FROM `user` WHERE `id` = ? -- :id\n AND `name` <> 'it''s :name' # :name\n;",
+			args:    []interface{}{1},
+		},
+		{
+			name:    "cast and system variables",
+			prepare: "SELECT `created_at`::date, @@session.time_zone, @@id FROM `user` WHERE `id` = :id;",
+			arg:     map[string]interface{}{"id": 1},
+			want:    "SELECT `created_at`::date, @@session.time_zone, @@id FROM `user` WHERE `id` = ?;",
+			args:    []interface{}{1},
+		},
+		{
+			name:    "user variable",
+			prepare: "SELECT @rank := @rank + 1, `name` FROM `user` WHERE `id` = :id;",
+			arg:     map[string]interface{}{"id": 1},
+			want:    "SELECT @rank := @rank + 1, `name` FROM `user` WHERE `id` = ?;",
+			args:    []interface{}{1},
+		},
+	}
+	for _, test := range tests {
+		prepare, args, err := NamedArgs(test.prepare, []interface{}{test.arg})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if prepare != test.want {
+			t.Errorf("%s: prepare %q, want %q", test.name, prepare, test.want)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%s: args %#v, want %#v", test.name, args, test.args)
+		}
+	}
+}
+
+func TestNamedArgsUnbound(t *testing.T) {
+	if _, _, err := NamedArgs("SELECT * FROM `user` WHERE `id` = :id;", []interface{}{map[string]interface{}{}}); err == nil {
+		t.Error("unbound :id is not reported")
+	}
+	if _, _, err := NamedArgs("SELECT * FROM `user` WHERE `id` = :Password;", []interface{}{namedUser{}}); err == nil {
+		t.Error("field tagged json:\"-\" is bound")
+	}
+	if _, _, err := NamedArgs("SELECT * FROM `user` WHERE `id` = :secret;", []interface{}{namedUser{}}); err == nil {
+		t.Error("unexported field is bound")
+	}
+	if _, _, err := NamedArgs("SELECT * FROM `user` WHERE `id` = :id AND `status` = ?;", []interface{}{map[string]interface{}{"id": 1}}); err == nil {
+		t.Error("named parameters mixed with positional parameters are bound")
+	}
+	if _, _, err := NamedArgs("SELECT '?' FROM `user` WHERE `id` = :id; -- ?", []interface{}{map[string]interface{}{"id": 1}}); err != nil {
+		t.Errorf("? inside literals and comments is taken as a positional parameter: %v", err)
+	}
+}
+
+func TestNamedArgsUnchanged(t *testing.T) {
+	tests := [][]interface{}{
+		nil,
+		{1},
+		{1, 2},
+		{time.Now()},
+		{[]byte("x")},
+	}
+	prepare := "SELECT * FROM `user` WHERE `id` = ? AND `name` = :name;"
+	for _, test := range tests {
+		result, args, err := NamedArgs(prepare, test)
+		if err != nil || result != prepare || !reflect.DeepEqual(args, test) {
+			t.Errorf("args %#v are bound: %q %#v %v", test, result, args, err)
+		}
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	valuer := sql.NullInt64{Int64: 1, Valid: true}
+	tests := []struct {
+		name    string
+		prepare string
+		args    []interface{}
+		want    string
+		result  []interface{}
+	}{
+		{
+			name:    "slice",
+			prepare: "SELECT * FROM `user` WHERE `id` IN ( ? ) AND `status` = ?;",
+			args:    []interface{}{[]int64{1, 2, 3}, 1},
+			want:    "SELECT * FROM `user` WHERE `id` IN ( ?, ?, ? ) AND `status` = ?;",
+			result:  []interface{}{int64(1), int64(2), int64(3), 1},
+		},
+		{
+			name:    "array",
+			prepare: "SELECT * FROM `user` WHERE `id` IN ( ? );",
+			args:    []interface{}{[2]string{"a", "b"}},
+			want:    "SELECT * FROM `user` WHERE `id` IN ( ?, ? );",
+			result:  []interface{}{"a", "b"},
+		},
+		{
+			name:    "bytes and valuer",
+			prepare: "SELECT * FROM `user` WHERE `hash` = ? AND `id` = ? AND `type` IN ( ? );",
+			args:    []interface{}{[]byte("x"), valuer, []int{1}},
+			want:    "SELECT * FROM `user` WHERE `hash` = ? AND `id` = ? AND `type` IN ( ? );",
+			result:  []interface{}{[]byte("x"), valuer, 1},
+		},
+		{
+			name:    "placeholders inside literals and comments",
+			prepare: "SELECT '?', \"?\", `?` /* ? */ FROM `user` WHERE `id` IN ( ? ) -- ?\n AND `name` = 'it''s ?' # ?\n;",
+			args:    []interface{}{[]int{1, 2}},
+			want:    "SELECT '?', \"?\", `?` /* ? */ FROM `user` WHERE `id` IN ( ?, ? ) -- ?\n AND `name` = 'it''s ?' # ?\n;",
+			result:  []interface{}{1, 2},
+		},
+		{
+			name:    "empty in",
+			prepare: "SELECT * FROM `user` WHERE `id` IN ( ? );",
+			args:    []interface{}{[]int{}},
+			want:    "SELECT * FROM `user` WHERE `id` IN ( SELECT NULL FROM DUAL WHERE 1 = 0 );",
+			result:  []interface{}{},
+		},
+		{
+			name:    "empty not in",
+			prepare: "SELECT * FROM `user` WHERE `id` not in(?) AND `status` = ?;",
+			args:    []interface{}{[]int{}, 1},
+			want:    "SELECT * FROM `user` WHERE `id` not in(SELECT NULL FROM DUAL WHERE 1 = 0) AND `status` = ?;",
+			result:  []interface{}{1},
+		},
+		{
+			name:    "empty elsewhere",
+			prepare: "SELECT * FROM `user` WHERE `id` = ? OR `bin` = MIN( ? );",
+			args:    []interface{}{[]int{}, []int{}},
+			want:    "SELECT * FROM `user` WHERE `id` = NULL OR `bin` = MIN( NULL );",
+			result:  []interface{}{},
+		},
+		{
+			name:    "nothing to expand",
+			prepare: "SELECT * FROM `user` WHERE `id` = ?;",
+			args:    []interface{}{1},
+			want:    "SELECT * FROM `user` WHERE `id` = ?;",
+			result:  []interface{}{1},
+		},
+	}
+	for _, test := range tests {
+		prepare, args := ExpandArgs(test.prepare, test.args)
+		if prepare != test.want {
+			t.Errorf("%s: prepare %q, want %q", test.name, prepare, test.want)
+		}
+		if !reflect.DeepEqual(args, test.result) {
+			t.Errorf("%s: args %#v, want %#v", test.name, args, test.result)
+		}
+	}
+}
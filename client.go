@@ -0,0 +1,330 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Client execute sql statements on a named database connection
+type Client struct {
+	name string // name of the registered database connection
+}
+
+// Use get the client of the database connection registered under name
+func Use(name string) *Client {
+	return &Client{
+		name: name,
+	}
+}
+
+// Hat database curd object of the client
+func (s *Client) Hat() *Hat {
+	return Db2(s.name)
+}
+
+// Curd insert, update, delete, select object of the client
+func (s *Client) Curd() *Curd {
+	return NewCurd(s.Hat())
+}
+
+// Query execute query sql
+func (s *Client) Query(scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
+	return s.QueryContext(context.Background(), scan, prepare, args...)
+}
+
+// QueryContext execute query sql with context
+func (s *Client) QueryContext(ctx context.Context, scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
+	return s.Hat().Scan(scan).Prepare(prepare).Args(args...).QueryContext(ctx)
+}
+
+// Execute execute non-query sql
+func (s *Client) Execute(prepare string, args ...interface{}) (int64, error) {
+	return s.ExecuteContext(context.Background(), prepare, args...)
+}
+
+// ExecuteContext execute non-query sql with context
+func (s *Client) ExecuteContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.Hat().Prepare(prepare).Args(args...).ExecuteContext(ctx)
+}
+
+// Transaction transaction execution, automatic rollback on error
+func (s *Client) Transaction(closure func(hat *Hat) (err error)) error {
+	return s.TransactionContext(context.Background(), closure)
+}
+
+// TransactionContext transaction execution with context, automatic rollback on error
+func (s *Client) TransactionContext(ctx context.Context, closure func(hat *Hat) (err error)) error {
+	return s.Hat().TransactionContext(ctx, closure)
+}
+
+// TransactionTx transaction execution with options such as isolation level and read only, automatic rollback on error
+func (s *Client) TransactionTx(ctx context.Context, opts *sql.TxOptions, closure func(hat *Hat) (err error)) error {
+	return s.Hat().TransactionTx(ctx, opts, closure)
+}
+
+// TransactionSnapshot transaction execution started with consistent snapshot, automatic rollback on error
+func (s *Client) TransactionSnapshot(ctx context.Context, readOnly bool, closure func(hat *Hat) (err error)) error {
+	return s.Hat().TransactionSnapshot(ctx, readOnly, closure)
+}
+
+// Create execute insert sql
+func (s *Client) Create(prepare string, args ...interface{}) (int64, error) {
+	return s.CreateContext(context.Background(), prepare, args...)
+}
+
+// CreateContext execute insert sql with context
+func (s *Client) CreateContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.Hat().Prepare(prepare).Args(args...).CreateContext(ctx)
+}
+
+// Count sql count rows
+func (s *Client) Count(prepare string, args ...interface{}) (int64, error) {
+	return s.CountContext(context.Background(), prepare, args...)
+}
+
+// CountContext sql count rows with context
+func (s *Client) CountContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.Hat().CountContext(ctx, prepare, args...)
+}
+
+// SumInt sql sum int
+func (s *Client) SumInt(prepare string, args ...interface{}) (int64, error) {
+	return s.SumIntContext(context.Background(), prepare, args...)
+}
+
+// SumIntContext sql sum int with context
+func (s *Client) SumIntContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.Hat().SumIntContext(ctx, prepare, args...)
+}
+
+// SumFloat sql sum float
+func (s *Client) SumFloat(prepare string, args ...interface{}) (float64, error) {
+	return s.SumFloatContext(context.Background(), prepare, args...)
+}
+
+// SumFloatContext sql sum float with context
+func (s *Client) SumFloatContext(ctx context.Context, prepare string, args ...interface{}) (float64, error) {
+	return s.Hat().SumFloatContext(ctx, prepare, args...)
+}
+
+// Exists sql data exists
+func (s *Client) Exists(prepare string, args ...interface{}) (bool, error) {
+	return s.ExistsContext(context.Background(), prepare, args...)
+}
+
+// ExistsContext sql data exists with context
+func (s *Client) ExistsContext(ctx context.Context, prepare string, args ...interface{}) (bool, error) {
+	return s.Hat().ExistsContext(ctx, prepare, args...)
+}
+
+// JsonFirst fetch first one using json
+func (s *Client) JsonFirst(fetch interface{}, prepare string, args ...interface{}) (bool, error) {
+	return s.JsonFirstContext(context.Background(), fetch, prepare, args...)
+}
+
+// JsonFirstContext fetch first one using json with context
+func (s *Client) JsonFirstContext(ctx context.Context, fetch interface{}, prepare string, args ...interface{}) (empty bool, err error) {
+	empty, err = s.Hat().Prepare(prepare).Args(args...).JsonFirstContext(ctx, fetch)
+	return
+}
+
+// JsonAll fetch all using json
+func (s *Client) JsonAll(fetch interface{}, prepare string, args ...interface{}) error {
+	return s.JsonAllContext(context.Background(), fetch, prepare, args...)
+}
+
+// JsonAllContext fetch all using json with context
+func (s *Client) JsonAllContext(ctx context.Context, fetch interface{}, prepare string, args ...interface{}) error {
+	return s.Hat().Prepare(prepare).Args(args...).JsonAllContext(ctx, fetch)
+}
+
+// GetFirst get first one
+func (s *Client) GetFirst(prepare string, args ...interface{}) (map[string]interface{}, error) {
+	return s.GetFirstContext(context.Background(), prepare, args...)
+}
+
+// GetFirstContext get first one with context
+func (s *Client) GetFirstContext(ctx context.Context, prepare string, args ...interface{}) (map[string]interface{}, error) {
+	return s.Hat().Prepare(prepare).Args(args...).GetFirstContext(ctx)
+}
+
+// GetAll get all
+func (s *Client) GetAll(prepare string, args ...interface{}) ([]map[string]interface{}, error) {
+	return s.GetAllContext(context.Background(), prepare, args...)
+}
+
+// GetAllContext get all with context
+func (s *Client) GetAllContext(ctx context.Context, prepare string, args ...interface{}) ([]map[string]interface{}, error) {
+	return s.Hat().Prepare(prepare).Args(args...).GetAllContext(ctx)
+}
+
+// GetFirstByte get first one
+func (s *Client) GetFirstByte(prepare string, args ...interface{}) (map[string][]byte, error) {
+	return s.GetFirstByteContext(context.Background(), prepare, args...)
+}
+
+// GetFirstByteContext get first one with context
+func (s *Client) GetFirstByteContext(ctx context.Context, prepare string, args ...interface{}) (map[string][]byte, error) {
+	return s.Hat().Prepare(prepare).Args(args...).GetFirstByteContext(ctx)
+}
+
+// GetAllByte get all
+func (s *Client) GetAllByte(prepare string, args ...interface{}) ([]map[string][]byte, error) {
+	return s.GetAllByteContext(context.Background(), prepare, args...)
+}
+
+// GetAllByteContext get all with context
+func (s *Client) GetAllByteContext(ctx context.Context, prepare string, args ...interface{}) ([]map[string][]byte, error) {
+	return s.Hat().Prepare(prepare).Args(args...).GetAllByteContext(ctx)
+}
+
+// Query execute query sql
+func Query(scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
+	return QueryContext(context.Background(), scan, prepare, args...)
+}
+
+// QueryContext execute query sql with context
+func QueryContext(ctx context.Context, scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
+	return Use(DefaultName).QueryContext(ctx, scan, prepare, args...)
+}
+
+// Execute execute non-query sql
+func Execute(prepare string, args ...interface{}) (int64, error) {
+	return ExecuteContext(context.Background(), prepare, args...)
+}
+
+// ExecuteContext execute non-query sql with context
+func ExecuteContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return Use(DefaultName).ExecuteContext(ctx, prepare, args...)
+}
+
+// Transaction transaction execution, automatic rollback on error
+func Transaction(closure func(hat *Hat) (err error)) error {
+	return TransactionContext(context.Background(), closure)
+}
+
+// TransactionContext transaction execution with context, automatic rollback on error
+func TransactionContext(ctx context.Context, closure func(hat *Hat) (err error)) error {
+	return Use(DefaultName).TransactionContext(ctx, closure)
+}
+
+// TransactionTx transaction execution with options such as isolation level and read only, automatic rollback on error
+func TransactionTx(ctx context.Context, opts *sql.TxOptions, closure func(hat *Hat) (err error)) error {
+	return Use(DefaultName).TransactionTx(ctx, opts, closure)
+}
+
+// TransactionSnapshot transaction execution started with consistent snapshot, automatic rollback on error
+func TransactionSnapshot(ctx context.Context, readOnly bool, closure func(hat *Hat) (err error)) error {
+	return Use(DefaultName).TransactionSnapshot(ctx, readOnly, closure)
+}
+
+// Create execute insert sql
+func Create(prepare string, args ...interface{}) (int64, error) {
+	return CreateContext(context.Background(), prepare, args...)
+}
+
+// CreateContext execute insert sql with context
+func CreateContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return Use(DefaultName).CreateContext(ctx, prepare, args...)
+}
+
+// Count sql count rows
+func Count(prepare string, args ...interface{}) (int64, error) {
+	return CountContext(context.Background(), prepare, args...)
+}
+
+// CountContext sql count rows with context
+func CountContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return Use(DefaultName).CountContext(ctx, prepare, args...)
+}
+
+// SumInt sql sum int
+func SumInt(prepare string, args ...interface{}) (int64, error) {
+	return SumIntContext(context.Background(), prepare, args...)
+}
+
+// SumIntContext sql sum int with context
+func SumIntContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return Use(DefaultName).SumIntContext(ctx, prepare, args...)
+}
+
+// SumFloat sql sum float
+func SumFloat(prepare string, args ...interface{}) (float64, error) {
+	return SumFloatContext(context.Background(), prepare, args...)
+}
+
+// SumFloatContext sql sum float with context
+func SumFloatContext(ctx context.Context, prepare string, args ...interface{}) (float64, error) {
+	return Use(DefaultName).SumFloatContext(ctx, prepare, args...)
+}
+
+// Exists sql data exists
+func Exists(prepare string, args ...interface{}) (bool, error) {
+	return ExistsContext(context.Background(), prepare, args...)
+}
+
+// ExistsContext sql data exists with context
+func ExistsContext(ctx context.Context, prepare string, args ...interface{}) (bool, error) {
+	return Use(DefaultName).ExistsContext(ctx, prepare, args...)
+}
+
+// JsonFirst fetch first one using json
+func JsonFirst(fetch interface{}, prepare string, args ...interface{}) (bool, error) {
+	return JsonFirstContext(context.Background(), fetch, prepare, args...)
+}
+
+// JsonFirstContext fetch first one using json with context
+func JsonFirstContext(ctx context.Context, fetch interface{}, prepare string, args ...interface{}) (empty bool, err error) {
+	empty, err = Use(DefaultName).JsonFirstContext(ctx, fetch, prepare, args...)
+	return
+}
+
+// JsonAll fetch all using json
+func JsonAll(fetch interface{}, prepare string, args ...interface{}) error {
+	return JsonAllContext(context.Background(), fetch, prepare, args...)
+}
+
+// JsonAllContext fetch all using json with context
+func JsonAllContext(ctx context.Context, fetch interface{}, prepare string, args ...interface{}) error {
+	return Use(DefaultName).JsonAllContext(ctx, fetch, prepare, args...)
+}
+
+// GetFirst get first one
+func GetFirst(prepare string, args ...interface{}) (map[string]interface{}, error) {
+	return GetFirstContext(context.Background(), prepare, args...)
+}
+
+// GetFirstContext get first one with context
+func GetFirstContext(ctx context.Context, prepare string, args ...interface{}) (map[string]interface{}, error) {
+	return Use(DefaultName).GetFirstContext(ctx, prepare, args...)
+}
+
+// GetAll get all
+func GetAll(prepare string, args ...interface{}) ([]map[string]interface{}, error) {
+	return GetAllContext(context.Background(), prepare, args...)
+}
+
+// GetAllContext get all with context
+func GetAllContext(ctx context.Context, prepare string, args ...interface{}) ([]map[string]interface{}, error) {
+	return Use(DefaultName).GetAllContext(ctx, prepare, args...)
+}
+
+// GetFirstByte get first one
+func GetFirstByte(prepare string, args ...interface{}) (map[string][]byte, error) {
+	return GetFirstByteContext(context.Background(), prepare, args...)
+}
+
+// GetFirstByteContext get first one with context
+func GetFirstByteContext(ctx context.Context, prepare string, args ...interface{}) (map[string][]byte, error) {
+	return Use(DefaultName).GetFirstByteContext(ctx, prepare, args...)
+}
+
+// GetAllByte get all
+func GetAllByte(prepare string, args ...interface{}) ([]map[string][]byte, error) {
+	return GetAllByteContext(context.Background(), prepare, args...)
+}
+
+// GetAllByteContext get all with context
+func GetAllByteContext(ctx context.Context, prepare string, args ...interface{}) ([]map[string][]byte, error) {
+	return Use(DefaultName).GetAllByteContext(ctx, prepare, args...)
+}
@@ -0,0 +1,176 @@
+package gomysql
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// Balance the policy of choosing a replica for read statements
+type Balance int
+
+const (
+	RoundRobin Balance = iota // take turns to use each replica
+	Weighted                  // take turns to use each replica in proportion to its weight
+)
+
+// Replica read only database connection of the cluster
+type Replica struct {
+	Name   string  // replica name, used to identify the replica
+	Db     *sql.DB // database connect object
+	Weight int     // replica weight, used by Weighted, a value less than 1 is treated as 1
+	state  int32   // replica health state, only healthy replicas are chosen
+}
+
+// State get the health state of the replica
+func (s *Replica) State() ReplicaState {
+	return ReplicaState(atomic.LoadInt32(&s.state))
+}
+
+// setState set the health state of the replica, return the previous state
+func (s *Replica) setState(state ReplicaState) ReplicaState {
+	return ReplicaState(atomic.SwapInt32(&s.state, int32(state)))
+}
+
+// available the replica can be chosen
+func (s *Replica) available() bool {
+	return s.Db != nil && s.State() == ReplicaHealthy
+}
+
+// weight the effective weight of the replica
+func (s *Replica) weight() int {
+	if s.Weight < 1 {
+		return 1
+	}
+	return s.Weight
+}
+
+// Cluster one primary database connection with several read only replicas
+// the read statements executed by Hat are routed to the replicas, the others are executed on the primary
+type Cluster struct {
+	primary  *sql.DB    // primary database connect object
+	replicas []*Replica // replicas
+	balance  Balance    // replica choosing policy
+	counter  uint64     // number of replicas chosen so far
+}
+
+// NewCluster create cluster object, use RoundRobin by default
+func NewCluster(primary *sql.DB, replicas ...*Replica) *Cluster {
+	return &Cluster{
+		primary:  primary,
+		replicas: replicas,
+	}
+}
+
+// Balance set the policy of choosing a replica
+func (s *Cluster) Balance(balance Balance) *Cluster {
+	s.balance = balance
+	return s
+}
+
+// Primary get the primary database connect object
+func (s *Cluster) Primary() *sql.DB {
+	return s.primary
+}
+
+// Replicas get all replicas of the cluster
+func (s *Cluster) Replicas() []*Replica {
+	return s.replicas
+}
+
+// reader choose the database connect object used by read statements
+// the primary is used if there is no replica or none of the replicas is healthy
+func (s *Cluster) reader() *sql.DB {
+	total, length := 0, 0
+	for _, replica := range s.replicas {
+		if replica.available() {
+			total += replica.weight()
+			length++
+		}
+	}
+	if length == 0 {
+		return s.primary
+	}
+	counter := atomic.AddUint64(&s.counter, 1) - 1
+	offset := int(counter % uint64(length))
+	if s.balance == Weighted {
+		offset = int(counter % uint64(total))
+	}
+	var last *Replica
+	for _, replica := range s.replicas {
+		if !replica.available() {
+			continue
+		}
+		last = replica
+		if s.balance == Weighted {
+			offset -= replica.weight()
+		} else {
+			offset--
+		}
+		if offset < 0 {
+			return replica.Db
+		}
+	}
+	// the health state changed while choosing
+	if last == nil {
+		return s.primary
+	}
+	return last.Db
+}
+
+// Available get the replicas that can currently be chosen
+func (s *Cluster) Available() []*Replica {
+	var available []*Replica
+	for _, replica := range s.replicas {
+		if replica.available() {
+			available = append(available, replica)
+		}
+	}
+	return available
+}
+
+// Close close the primary and all replicas, return the first error encountered
+func (s *Cluster) Close() (err error) {
+	if s.primary != nil {
+		err = s.primary.Close()
+	}
+	for _, replica := range s.replicas {
+		if replica.Db == nil {
+			continue
+		}
+		if tmp := replica.Db.Close(); tmp != nil && err == nil {
+			err = tmp
+		}
+	}
+	return
+}
+
+// OpenCluster connect to the primary and replicas, register the cluster under name
+// dn: driver name, primary: data source name of primary, replicas: data source names of replicas
+func OpenCluster(name string, dn string, primary string, replicas ...string) error {
+	return OpenClusterOptions(name, dn, DefaultOptions(), primary, replicas...)
+}
+
+// OpenClusterOptions connect to the primary and replicas with options, register the cluster under name
+// dn: driver name, primary: data source name of primary, replicas: data source names of replicas
+func OpenClusterOptions(name string, dn string, options *Options, primary string, replicas ...string) error {
+	pri, err := NewDb(dn, primary, options)
+	if err != nil {
+		return err
+	}
+	cluster := NewCluster(pri)
+	for key, dsn := range replicas {
+		var rep *sql.DB
+		rep, err = NewDb(dn, dsn, options)
+		if err != nil {
+			_ = cluster.Close()
+			return err
+		}
+		cluster.replicas = append(cluster.replicas, &Replica{
+			Name: fmt.Sprintf("replica%d", key),
+			Db:   rep,
+		})
+	}
+	SetCluster(name, cluster)
+	return nil
+}
@@ -0,0 +1,262 @@
+package gomysql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Condition condition of WHERE, PrepareArgs renders the sql condition and its parameters, the columns are quoted by quoteColumn
+type Condition interface {
+	PrepareArgs() (prepare string, args []interface{})
+}
+
+const (
+	conditionTrue  = "1 = 1" // the condition always true, such as And() and NotIn with no values
+	conditionFalse = "1 = 0" // the condition always false, such as Or() and In with no values
+)
+
+// condition rendered sql condition
+type condition struct {
+	prepare string        // sql condition
+	args    []interface{} // parameters of sql condition
+}
+
+// PrepareArgs get sql condition and parameter list of sql condition
+func (s *condition) PrepareArgs() (string, []interface{}) {
+	return s.prepare, s.args
+}
+
+// Raw sql condition with its parameters, it is used as it is
+func Raw(prepare string, args ...interface{}) Condition {
+	return &condition{prepare: prepare, args: args}
+}
+
+// quoteColumn quote the column of condition strictly, such as u.id => `u`.`id`, the backticks of name are removed
+// unlike Identifier a name containing ( is quoted too, so the column can not inject sql
+func quoteColumn(name string) string {
+	parts := strings.Split(strings.ReplaceAll(name, Backtick, ""), ".")
+	for key, part := range parts {
+		parts[key] = fmt.Sprintf("%s%s%s", Backtick, strings.TrimSpace(part), Backtick)
+	}
+	return strings.Join(parts, ".")
+}
+
+// elements get the elements of the slice or array value
+func elements(value interface{}) []interface{} {
+	tmp := reflect.ValueOf(value)
+	length := tmp.Len()
+	result := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		result = append(result, tmp.Index(i).Interface())
+	}
+	return result
+}
+
+// compare column compared with value by operator
+func compare(column string, operator string, value interface{}) Condition {
+	return &condition{
+		prepare: fmt.Sprintf("%s %s ?", quoteColumn(column), operator),
+		args:    []interface{}{value},
+	}
+}
+
+// Eq column = value, column IS NULL if value is nil, column IN (values...) if value is a slice except []byte
+func Eq(column string, value interface{}) Condition {
+	if value == nil {
+		return IsNull(column)
+	}
+	if expandable(value) {
+		return In(column, elements(value)...)
+	}
+	return compare(column, "=", value)
+}
+
+// Ne column <> value, column IS NOT NULL if value is nil, column NOT IN (values...) if value is a slice except []byte
+func Ne(column string, value interface{}) Condition {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	if expandable(value) {
+		return NotIn(column, elements(value)...)
+	}
+	return compare(column, "<>", value)
+}
+
+// On column left = column right, such as the condition of JOIN u.id = o.user_id
+func On(left string, right string) Condition {
+	return &condition{prepare: fmt.Sprintf("%s = %s", quoteColumn(left), quoteColumn(right))}
+}
+
+// Gt column > value
+func Gt(column string, value interface{}) Condition {
+	return compare(column, ">", value)
+}
+
+// Ge column >= value
+func Ge(column string, value interface{}) Condition {
+	return compare(column, ">=", value)
+}
+
+// Lt column < value
+func Lt(column string, value interface{}) Condition {
+	return compare(column, "<", value)
+}
+
+// Le column <= value
+func Le(column string, value interface{}) Condition {
+	return compare(column, "<=", value)
+}
+
+// Like column LIKE pattern
+func Like(column string, pattern string) Condition {
+	return compare(column, "LIKE", pattern)
+}
+
+// IsNull column IS NULL
+func IsNull(column string) Condition {
+	return &condition{prepare: fmt.Sprintf("%s IS NULL", quoteColumn(column))}
+}
+
+// IsNotNull column IS NOT NULL
+func IsNotNull(column string) Condition {
+	return &condition{prepare: fmt.Sprintf("%s IS NOT NULL", quoteColumn(column))}
+}
+
+// Between column BETWEEN start AND end
+func Between(column string, start interface{}, end interface{}) Condition {
+	return &condition{
+		prepare: fmt.Sprintf("%s BETWEEN ? AND ?", quoteColumn(column)),
+		args:    []interface{}{start, end},
+	}
+}
+
+// in column IN (?, ?, ...) or column NOT IN (?, ?, ...)
+func in(column string, operator string, values []interface{}) *condition {
+	return &condition{
+		prepare: fmt.Sprintf("%s %s ( %s )", quoteColumn(column), operator, strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")),
+		args:    values,
+	}
+}
+
+// In column IN (values...), it is always false if values is empty
+func In(column string, values ...interface{}) Condition {
+	if len(values) == 0 {
+		return &condition{prepare: conditionFalse}
+	}
+	return in(column, "IN", values)
+}
+
+// NotIn column NOT IN (values...), it is always true if values is empty
+func NotIn(column string, values ...interface{}) Condition {
+	if len(values) == 0 {
+		return &condition{prepare: conditionTrue}
+	}
+	return in(column, "NOT IN", values)
+}
+
+// join join the conditions by operator, every condition is enclosed in parentheses, nil conditions and the conditions equal to empty are skipped
+// the result is absorbing if any condition equals it, such as 1 = 0 of AND, so the conditions always true or false are rendered as they are
+func join(operator string, empty string, absorbing string, conditions []Condition) Condition {
+	var prepare []string
+	var args []interface{}
+	for _, tmp := range conditions {
+		if tmp == nil {
+			continue
+		}
+		where, params := tmp.PrepareArgs()
+		if where == "" || where == empty {
+			continue
+		}
+		if where == absorbing {
+			return &condition{prepare: absorbing}
+		}
+		prepare = append(prepare, where)
+		args = append(args, params...)
+	}
+	if len(prepare) == 0 {
+		return &condition{prepare: empty}
+	}
+	if len(prepare) == 1 {
+		return &condition{prepare: prepare[0], args: args}
+	}
+	return &condition{
+		prepare: fmt.Sprintf("( %s )", strings.Join(prepare, fmt.Sprintf(" ) %s ( ", operator))),
+		args:    args,
+	}
+}
+
+// And all the conditions are true, it is always true if there is no condition
+func And(conditions ...Condition) Condition {
+	return join("AND", conditionTrue, conditionFalse, conditions)
+}
+
+// Or any of the conditions is true, it is always false if there is no condition
+func Or(conditions ...Condition) Condition {
+	return join("OR", conditionFalse, conditionTrue, conditions)
+}
+
+// Not the condition is false
+func Not(condition Condition) Condition {
+	where, args := condition.PrepareArgs()
+	switch where {
+	case conditionTrue:
+		return Raw(conditionFalse)
+	case conditionFalse:
+		return Raw(conditionTrue)
+	}
+	return Raw(fmt.Sprintf("NOT ( %s )", where), args...)
+}
+
+// Where shorthand of conditions, every column equals its value by Eq and they are joined by AND, such as Where{"id": 1, "deleted_at": nil, "status": []int{1, 2}}
+type Where map[string]interface{}
+
+// PrepareArgs get sql condition and parameter list of sql condition, the columns are sorted to render the same sql every time
+func (s Where) PrepareArgs() (string, []interface{}) {
+	columns := make([]string, 0, len(s))
+	for column := range s {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	conditions := make([]Condition, 0, len(columns))
+	for _, column := range columns {
+		conditions = append(conditions, Eq(column, s[column]))
+	}
+	return And(conditions...).PrepareArgs()
+}
+
+// filterArgs render where of the Curd methods deleting or modifying rows, a Condition or a map always true is refused
+// so an empty filter built dynamically does not affect all rows, an empty string or nil where still affects all rows
+func filterArgs(where interface{}, args []interface{}) (string, []interface{}, error) {
+	cond, args, err := whereArgs(where, args)
+	if err != nil {
+		return cond, args, err
+	}
+	if _, ok := where.(string); !ok && where != nil && (cond == "" || cond == conditionTrue) {
+		return "", nil, errors.New("the where condition is always true, please use an empty string to affect all rows")
+	}
+	return cond, args, nil
+}
+
+// whereArgs render where of Curd methods, where is a sql condition string with args, a Condition, or a map[string]interface{} as Where
+func whereArgs(where interface{}, args []interface{}) (string, []interface{}, error) {
+	switch tmp := where.(type) {
+	case nil:
+		return "", nil, nil
+	case string:
+		return tmp, args, nil
+	case map[string]interface{}:
+		where = Where(tmp)
+	}
+	condition, ok := where.(Condition)
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported where type %T", where)
+	}
+	if len(args) > 0 {
+		return "", nil, errors.New("args are not allowed when where is a Condition")
+	}
+	prepare, args := condition.PrepareArgs()
+	return prepare, args, nil
+}
@@ -0,0 +1,52 @@
+package gomysql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		want      string
+		args      []interface{}
+	}{
+		{"eq", Eq("u.id", 1), "`u`.`id` = ?", []interface{}{1}},
+		{"eq nil", Eq("deleted_at", nil), "`deleted_at` IS NULL", nil},
+		{"eq slice", Eq("id", []int{1, 2}), "`id` IN ( ?, ? )", []interface{}{1, 2}},
+		{"eq bytes", Eq("hash", []byte("x")), "`hash` = ?", []interface{}{[]byte("x")}},
+		{"ne slice", Ne("id", []int64{3}), "`id` NOT IN ( ? )", []interface{}{int64(3)}},
+		{"column injection", Eq("id) OR (1=1", 1), "`id) OR (1=1` = ?", []interface{}{1}},
+		{"column backtick", Eq("`id` OR 1", 1), "`id OR 1` = ?", []interface{}{1}},
+		{"where", Where{"status": []int{1, 2}, "id": 1}, "( `id` = ? ) AND ( `status` IN ( ?, ? ) )", []interface{}{1, 1, 2}},
+		{"and empty", And(), conditionTrue, nil},
+		{"and of empty", And(And(), Where{}, NotIn("id")), conditionTrue, nil},
+		{"and false", And(Eq("id", 1), In("id")), conditionFalse, nil},
+		{"or empty", Or(), conditionFalse, nil},
+		{"or true", Or(Eq("id", 1), And()), conditionTrue, nil},
+		{"not true", Not(And()), conditionFalse, nil},
+		{"not", Not(Eq("id", 1)), "NOT ( `id` = ? )", []interface{}{1}},
+	}
+	for _, test := range tests {
+		prepare, args := test.condition.PrepareArgs()
+		if prepare != test.want || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%s: %q %#v, want %q %#v", test.name, prepare, args, test.want, test.args)
+		}
+	}
+}
+
+func TestFilterArgs(t *testing.T) {
+	refused := []interface{}{Where{}, map[string]interface{}{}, And(), Or(Eq("id", 1), NotIn("id")), Raw("")}
+	for _, where := range refused {
+		if _, _, err := filterArgs(where, nil); err == nil {
+			t.Errorf("where %#v always true is not refused", where)
+		}
+	}
+	allowed := []interface{}{nil, "", "`id` = ?", Where{"id": 1}, In("id")}
+	for _, where := range allowed {
+		if _, _, err := filterArgs(where, nil); err != nil {
+			t.Errorf("where %#v is refused: %v", where, err)
+		}
+	}
+}
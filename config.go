@@ -0,0 +1,82 @@
+package gomysql
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Config mysql connection config, used to build the data source name
+type Config struct {
+	Host      string            `json:"host"`      // host, default 127.0.0.1
+	Port      int               `json:"port"`      // port, default 3306
+	Socket    string            `json:"socket"`    // unix socket path, Host and Port are ignored if it is set
+	User      string            `json:"user"`      // username
+	Password  string            `json:"password"`  // password
+	Database  string            `json:"database"`  // database name
+	Charset   string            `json:"charset"`   // connection charset, such as utf8mb4
+	Collation string            `json:"collation"` // connection collation, such as utf8mb4_unicode_ci
+	Params    map[string]string `json:"params"`    // other connection parameters, such as parseTime=true, loc=Local
+}
+
+// mysql convert to the config of mysql driver
+func (s *Config) mysql() (*mysql.Config, error) {
+	params := make(map[string]string, len(s.Params)+1)
+	for key, val := range s.Params {
+		params[key] = val
+	}
+	if s.Charset != "" {
+		params["charset"] = s.Charset
+	}
+	if s.Collation != "" {
+		params["collation"] = s.Collation
+	}
+	// let the driver parse the parameters, such as parseTime, loc, timeout
+	cfg := mysql.NewConfig()
+	cfg.Params = params
+	cfg, err := mysql.ParseDSN(cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	if s.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = s.Socket
+	} else {
+		host, port := s.Host, s.Port
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		if port <= 0 {
+			port = 3306
+		}
+		cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	cfg.User = s.User
+	cfg.Passwd = s.Password
+	cfg.DBName = s.Database
+	return cfg, nil
+}
+
+// DSN build data source name
+// username:password@tcp(host:port)/database?charset=utf8mb4&collation=utf8mb4_unicode_ci
+func (s *Config) DSN() (string, error) {
+	cfg, err := s.mysql()
+	if err != nil {
+		return "", err
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// OpenConfig connect to mysql service using config and options, register the connection under name
+// ConnectTimeout of options is used as dial timeout if the params of config do not set timeout
+func OpenConfig(name string, config *Config, options *Options) error {
+	cfg, err := config.mysql()
+	if err != nil {
+		return err
+	}
+	if options != nil && options.ConnectTimeout > 0 && cfg.Timeout == 0 {
+		cfg.Timeout = options.ConnectTimeout
+	}
+	return OpenOptions(name, "mysql", cfg.FormatDSN(), options)
+}
@@ -0,0 +1,135 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Credential username and password of database connection
+type Credential struct {
+	User     string
+	Password string
+}
+
+// CredentialProvider get the current credential, it is called for every new physical connection
+type CredentialProvider func(ctx context.Context) (*Credential, error)
+
+// connector create every physical connection with the current credential
+type connector struct {
+	cfg      *mysql.Config      // config of mysql driver, the user and password are replaced by the credential
+	provider CredentialProvider // credential provider
+}
+
+// Connect create a physical connection with the current credential
+func (s *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	credential, err := s.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, errors.New("credential provider returned nil credential")
+	}
+	cfg := s.cfg.Clone()
+	cfg.User = credential.User
+	cfg.Passwd = credential.Password
+	tmp, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return tmp.Connect(ctx)
+}
+
+// Driver mysql driver
+func (s *connector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
+
+// NewConnector create connector of config, the user and password of config are ignored, the provider supplies them for every new physical connection
+// the pooled connections keep the credential they were created with, set ConnMaxLifetime of Options to let them drain
+func NewConnector(config *Config, provider CredentialProvider) (driver.Connector, error) {
+	if provider == nil {
+		return nil, errors.New("credential provider is nil")
+	}
+	cfg, err := config.mysql()
+	if err != nil {
+		return nil, err
+	}
+	return &connector{
+		cfg:      cfg,
+		provider: provider,
+	}, nil
+}
+
+// OpenCredential connect to mysql service using config, credential provider and options, register the connection under name
+// ConnectTimeout of options is used as dial timeout if the params of config do not set timeout
+func OpenCredential(name string, config *Config, provider CredentialProvider, options *Options) error {
+	if provider == nil {
+		return errors.New("credential provider is nil")
+	}
+	cfg, err := config.mysql()
+	if err != nil {
+		return err
+	}
+	if options != nil && options.ConnectTimeout > 0 && cfg.Timeout == 0 {
+		cfg.Timeout = options.ConnectTimeout
+	}
+	database, err := NewDbConnector(&connector{
+		cfg:      cfg,
+		provider: provider,
+	}, options)
+	if err != nil {
+		return err
+	}
+	SetName(name, database)
+	return nil
+}
+
+// StaticCredential credential provider that always returns the same credential
+func StaticCredential(user string, password string) CredentialProvider {
+	credential := &Credential{
+		User:     user,
+		Password: password,
+	}
+	return func(ctx context.Context) (*Credential, error) {
+		return credential, nil
+	}
+}
+
+// CachedCredential credential provider that calls provider at most once every ttl, the cached credential is used in between
+func CachedCredential(provider CredentialProvider, ttl time.Duration) CredentialProvider {
+	cache := &credentialCache{
+		provider: provider,
+		ttl:      ttl,
+	}
+	return cache.get
+}
+
+// credentialCache cache of credential provider
+type credentialCache struct {
+	mutex    sync.Mutex
+	provider CredentialProvider
+	ttl      time.Duration
+	value    *Credential
+	expire   time.Time
+}
+
+// get get the cached credential, refresh it if expired
+func (s *credentialCache) get(ctx context.Context) (*Credential, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.value != nil && time.Now().Before(s.expire) {
+		return s.value, nil
+	}
+	credential, err := s.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.value = credential
+	s.expire = time.Now().Add(s.ttl)
+	return credential, nil
+}
@@ -0,0 +1,29 @@
+package gomysql
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCurdShared run the builders of one curd from many goroutines, go test -race reports the data races
+func TestCurdShared(t *testing.T) {
+	curd := NewCurdName("gomysql_test_shared")
+	var wait sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			tmp := curd.Primary().Timeout(time.Second).Lock(ForUpdate)
+			_, _ = tmp.GetAll("SELECT * FROM `user` WHERE ( `id` = ? );", i)
+			hat := curd.hat.Prepare("SELECT * FROM `user` WHERE ( `id` = ? );").Args(i).Scan(func(rows *sql.Rows) error { return nil })
+			_ = hat.Query()
+			_, _ = curd.SetTimeout(time.Second).Replica().PrepareArgs()
+		}(i)
+	}
+	wait.Wait()
+	if curd.hat.primary || curd.hat.timeout != 0 || curd.hat.once != 0 || curd.hat.lock != 0 || curd.hat.prepare != "" || curd.hat.args != nil || curd.hat.scan != nil {
+		t.Fatal("the shared curd is changed by its copies")
+	}
+}
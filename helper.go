@@ -1,12 +1,14 @@
 package gomysql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 )
 
 const (
@@ -50,8 +52,15 @@ type Curd struct {
 	AddAt func() map[string]interface{}
 	ModAt func() map[string]interface{}
 	DelAt func() map[string]interface{}
+	// Version column name of optimistic locking, the updated map of Mod must contain the current version of the row,
+	// the row is updated only if its version is still the same, and its version is increased by 1
+	Version string
 }
 
+// ErrVersionConflict the row was modified by others since its version was read, or the row does not exist
+var ErrVersionConflict = errors.New("optimistic locking version conflict")
+
+// NewCurd create curd object, use the last hat if given, otherwise the default database connection
 func NewCurd(hat ...*Hat) (curd *Curd) {
 	curd = &Curd{}
 	length := len(hat)
@@ -63,24 +72,49 @@ func NewCurd(hat ...*Hat) (curd *Curd) {
 	return
 }
 
+// NewCurdName create curd object using the database connection registered under name
+func NewCurdName(name string) *Curd {
+	return NewCurd(Db2(name))
+}
+
 // Transaction closures execute transaction, err != nil auto rollback
-func (s *Curd) Transaction(closure func(curd *Curd) (err error)) (err error) {
-	err = s.Begin()
-	if err != nil {
-		return
-	}
-	err = closure(s)
-	if err != nil {
-		_ = s.Rollback()
-		return
-	}
-	_ = s.Commit()
-	return
+func (s *Curd) Transaction(closure func(curd *Curd) (err error)) error {
+	return s.TransactionContext(context.Background(), closure)
+}
+
+// TransactionContext closures execute transaction with context, err != nil auto rollback
+func (s *Curd) TransactionContext(ctx context.Context, closure func(curd *Curd) (err error)) error {
+	return s.TransactionTx(ctx, nil, closure)
+}
+
+// TransactionTx closures execute transaction with options such as isolation level and read only, err != nil auto rollback
+func (s *Curd) TransactionTx(ctx context.Context, opts *sql.TxOptions, closure func(curd *Curd) (err error)) error {
+	return s.hat.TransactionTx(ctx, opts, func(hat *Hat) error { return closure(s.clone(hat)) })
+}
+
+// TransactionSnapshot closures execute transaction started with consistent snapshot, err != nil auto rollback
+func (s *Curd) TransactionSnapshot(ctx context.Context, readOnly bool, closure func(curd *Curd) (err error)) error {
+	return s.hat.TransactionSnapshot(ctx, readOnly, func(hat *Hat) error { return closure(s.clone(hat)) })
 }
 
 // Begin start a transaction
 func (s *Curd) Begin() error {
-	return s.hat.Begin()
+	return s.BeginContext(context.Background())
+}
+
+// BeginContext start a transaction with context
+func (s *Curd) BeginContext(ctx context.Context) error {
+	return s.hat.BeginContext(ctx)
+}
+
+// BeginTx start a transaction with options such as isolation level and read only
+func (s *Curd) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
+	return s.hat.BeginTx(ctx, opts)
+}
+
+// BeginSnapshot start a transaction using START TRANSACTION WITH CONSISTENT SNAPSHOT
+func (s *Curd) BeginSnapshot(ctx context.Context, readOnly bool) error {
+	return s.hat.BeginSnapshot(ctx, readOnly)
 }
 
 // Rollback transaction rollback
@@ -93,41 +127,105 @@ func (s *Curd) Commit() error {
 	return s.hat.Commit()
 }
 
-// PrepareArgs get prepared sql statement and parameter list of prepared sql statement
+// Primary get a copy of curd executing the read statements on the primary database connection, use it to read your own writes
+func (s *Curd) Primary() *Curd {
+	return s.clone(s.hat.Primary())
+}
+
+// Replica get a copy of curd executing the read statements on the replicas if the database connection is a cluster
+func (s *Curd) Replica() *Curd {
+	return s.clone(s.hat.Replica())
+}
+
+// SetTimeout get a copy of curd with the default timeout of every statement, 0 means no timeout
+func (s *Curd) SetTimeout(timeout time.Duration) *Curd {
+	return s.clone(s.hat.SetTimeout(timeout))
+}
+
+// Timeout get a copy of curd executing statements within timeout, overrides the default timeout
+func (s *Curd) Timeout(timeout time.Duration) *Curd {
+	return s.clone(s.hat.Timeout(timeout))
+}
+
+// Clone copy curd, the copy shares the database session of curd
+func (s *Curd) Clone() *Curd {
+	return s.clone(s.hat.Clone())
+}
+
+// clone copy curd using hat
+func (s *Curd) clone(hat *Hat) *Curd {
+	tmp := *s
+	tmp.hat = hat
+	return &tmp
+}
+
+// PrepareArgs get the last sql statement and parameter list executed by curd or any of its copies sharing the session
+// it is meaningless on a curd shared by goroutines, the statement of another goroutine may be returned,
+// use the hat holding the statement instead, such as hat := Db2().Prepare(prepare).Args(args...) and hat.PrepareArgs()
 func (s *Curd) PrepareArgs() (prepare string, args []interface{}) {
-	prepare, args = s.hat.PrepareArgs()
+	prepare, args = s.hat.session.last()
 	return
 }
 
 // JsonFirst fetch first one using json
-func (s *Curd) JsonFirst(fetch interface{}, prepare string, args ...interface{}) (empty bool, err error) {
-	empty, err = s.hat.Prepare(prepare).Args(args...).JsonFirst(fetch)
+func (s *Curd) JsonFirst(fetch interface{}, prepare string, args ...interface{}) (bool, error) {
+	return s.JsonFirstContext(context.Background(), fetch, prepare, args...)
+}
+
+// JsonFirstContext fetch first one using json with context
+func (s *Curd) JsonFirstContext(ctx context.Context, fetch interface{}, prepare string, args ...interface{}) (empty bool, err error) {
+	empty, err = s.hat.Prepare(prepare).Args(args...).JsonFirstContext(ctx, fetch)
 	return
 }
 
 // JsonAll fetch all using json
 func (s *Curd) JsonAll(fetch interface{}, prepare string, args ...interface{}) error {
-	return s.hat.Prepare(prepare).Args(args...).JsonAll(fetch)
+	return s.JsonAllContext(context.Background(), fetch, prepare, args...)
+}
+
+// JsonAllContext fetch all using json with context
+func (s *Curd) JsonAllContext(ctx context.Context, fetch interface{}, prepare string, args ...interface{}) error {
+	return s.hat.Prepare(prepare).Args(args...).JsonAllContext(ctx, fetch)
 }
 
 // GetFirst get first one
 func (s *Curd) GetFirst(prepare string, args ...interface{}) (map[string]interface{}, error) {
-	return s.hat.Prepare(prepare).Args(args...).GetFirst()
+	return s.GetFirstContext(context.Background(), prepare, args...)
+}
+
+// GetFirstContext get first one with context
+func (s *Curd) GetFirstContext(ctx context.Context, prepare string, args ...interface{}) (map[string]interface{}, error) {
+	return s.hat.Prepare(prepare).Args(args...).GetFirstContext(ctx)
 }
 
 // GetAll get all
 func (s *Curd) GetAll(prepare string, args ...interface{}) ([]map[string]interface{}, error) {
-	return s.hat.Prepare(prepare).Args(args...).GetAll()
+	return s.GetAllContext(context.Background(), prepare, args...)
+}
+
+// GetAllContext get all with context
+func (s *Curd) GetAllContext(ctx context.Context, prepare string, args ...interface{}) ([]map[string]interface{}, error) {
+	return s.hat.Prepare(prepare).Args(args...).GetAllContext(ctx)
 }
 
 // GetFirstByte get first one
 func (s *Curd) GetFirstByte(prepare string, args ...interface{}) (map[string][]byte, error) {
-	return s.hat.Prepare(prepare).Args(args...).GetFirstByte()
+	return s.GetFirstByteContext(context.Background(), prepare, args...)
+}
+
+// GetFirstByteContext get first one with context
+func (s *Curd) GetFirstByteContext(ctx context.Context, prepare string, args ...interface{}) (map[string][]byte, error) {
+	return s.hat.Prepare(prepare).Args(args...).GetFirstByteContext(ctx)
 }
 
 // GetAllByte get all
 func (s *Curd) GetAllByte(prepare string, args ...interface{}) ([]map[string][]byte, error) {
-	return s.hat.Prepare(prepare).Args(args...).GetAllByte()
+	return s.GetAllByteContext(context.Background(), prepare, args...)
+}
+
+// GetAllByteContext get all with context
+func (s *Curd) GetAllByteContext(ctx context.Context, prepare string, args ...interface{}) ([]map[string][]byte, error) {
+	return s.hat.Prepare(prepare).Args(args...).GetAllByteContext(ctx)
 }
 
 // JsonTransfer data exchange by json, map[string]interface{} <=> *AnyStruct , []map[string]interface{} <=> *[]AnyStruct | *[]*AnyStruct
@@ -137,17 +235,32 @@ func (s *Curd) JsonTransfer(source interface{}, result interface{}) error {
 
 // Query execute any query sql
 func (s *Curd) Query(scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
-	return s.hat.Scan(scan).Prepare(prepare).Args(args...).Query()
+	return s.QueryContext(context.Background(), scan, prepare, args...)
+}
+
+// QueryContext execute any query sql with context
+func (s *Curd) QueryContext(ctx context.Context, scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
+	return s.hat.Scan(scan).Prepare(prepare).Args(args...).QueryContext(ctx)
 }
 
 // Execute execute any non-query sql
 func (s *Curd) Execute(prepare string, args ...interface{}) (int64, error) {
-	return s.hat.Prepare(prepare).Args(args...).Execute()
+	return s.ExecuteContext(context.Background(), prepare, args...)
+}
+
+// ExecuteContext execute any non-query sql with context
+func (s *Curd) ExecuteContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.hat.Prepare(prepare).Args(args...).ExecuteContext(ctx)
 }
 
 // Create execute an insert sql
 func (s *Curd) Create(prepare string, args ...interface{}) (int64, error) {
-	return s.hat.Prepare(prepare).Args(args...).Create()
+	return s.CreateContext(context.Background(), prepare, args...)
+}
+
+// CreateContext execute an insert sql with context
+func (s *Curd) CreateContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.hat.Prepare(prepare).Args(args...).CreateContext(ctx)
 }
 
 // table cout table name
@@ -196,7 +309,12 @@ func (s *Curd) addAt(msi map[string]interface{}, add func() map[string]interface
 }
 
 // Add insert a piece of data
-func (s *Curd) Add(add interface{}, table ...interface{}) (id int64, err error) {
+func (s *Curd) Add(add interface{}, table ...interface{}) (int64, error) {
+	return s.AddContext(context.Background(), add, table...)
+}
+
+// AddContext insert a piece of data with context
+func (s *Curd) AddContext(ctx context.Context, add interface{}, table ...interface{}) (id int64, err error) {
 	if add == nil {
 		err = errors.New("insert object is nil")
 		return
@@ -245,29 +363,49 @@ func (s *Curd) Add(add interface{}, table ...interface{}) (id int64, err error)
 		fmt.Sprintf("%s%s%s", Backtick, strings.Join(columns, fmt.Sprintf("%s, %s", Backtick, Backtick)), Backtick),
 		strings.Join(values, ", "),
 	)
-	id, err = s.Create(prepare, args...)
+	id, err = s.CreateContext(ctx, prepare, args...)
 	return
 }
 
-// Del delete using where
-func (s *Curd) Del(table interface{}, where string, args ...interface{}) (int64, error) {
+// Del delete using where, where is a sql condition string with args, a Condition, or a map[string]interface{} of columns equal to their values
+// a Condition or map always true, such as an empty map, is refused, an empty string where affects all rows
+func (s *Curd) Del(table interface{}, where interface{}, args ...interface{}) (int64, error) {
+	return s.DelContext(context.Background(), table, where, args...)
+}
+
+// DelContext delete using where with context
+func (s *Curd) DelContext(ctx context.Context, table interface{}, where interface{}, args ...interface{}) (int64, error) {
 	tab := s.table(table)
 	if tab == "" {
 		return 0, errors.New("please set table name first")
 	}
-	if where == "" {
-		return s.Execute(fmt.Sprintf("DELETE FROM %s;", Identifier(tab)))
+	cond, args, err := filterArgs(where, args)
+	if err != nil {
+		return 0, err
 	}
-	return s.Execute(fmt.Sprintf("DELETE FROM %s WHERE ( %s );", Identifier(tab), where), args...)
+	if cond == "" {
+		return s.ExecuteContext(ctx, fmt.Sprintf("DELETE FROM %s;", Identifier(tab)))
+	}
+	return s.ExecuteContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE ( %s );", Identifier(tab), cond), args...)
 }
 
 // DelId delete using id
 func (s *Curd) DelId(table interface{}, id interface{}) (int64, error) {
-	return s.Del(table, ideq, id)
+	return s.DelIdContext(context.Background(), table, id)
+}
+
+// DelIdContext delete using id with context
+func (s *Curd) DelIdContext(ctx context.Context, table interface{}, id interface{}) (int64, error) {
+	return s.DelContext(ctx, table, ideq, id)
 }
 
-// FakDel fake delete using where
-func (s *Curd) FakDel(table interface{}, where string, args ...interface{}) (int64, error) {
+// FakDel fake delete using where, a Condition or map always true is refused like Del
+func (s *Curd) FakDel(table interface{}, where interface{}, args ...interface{}) (int64, error) {
+	return s.FakDelContext(context.Background(), table, where, args...)
+}
+
+// FakDelContext fake delete using where with context
+func (s *Curd) FakDelContext(ctx context.Context, table interface{}, where interface{}, args ...interface{}) (int64, error) {
 	if s.DelAt == nil {
 		return 0, errors.New("please set the pseudo delete handler first")
 	}
@@ -280,49 +418,121 @@ func (s *Curd) FakDel(table interface{}, where string, args ...interface{}) (int
 	if tab == "" {
 		return 0, errors.New("please set table name first")
 	}
+	cond, args, err := filterArgs(where, args)
+	if err != nil {
+		return 0, err
+	}
 	key, val := ModifyPrepareArgs(update)
 	prepare := ""
-	if where == "" {
+	if cond == "" {
 		prepare = fmt.Sprintf("UPDATE %s SET %s;", Identifier(tab), key)
 	} else {
-		prepare = fmt.Sprintf("UPDATE %s SET %s WHERE ( %s );", Identifier(tab), key, where)
+		prepare = fmt.Sprintf("UPDATE %s SET %s WHERE ( %s );", Identifier(tab), key, cond)
 		val = append(val, args...)
 	}
-	return s.Execute(prepare, val...)
+	return s.ExecuteContext(ctx, prepare, val...)
 }
 
 // FakDelId fake delete using id
 func (s *Curd) FakDelId(table interface{}, id interface{}) (int64, error) {
-	return s.FakDel(table, ideq, id)
+	return s.FakDelIdContext(context.Background(), table, id)
+}
+
+// FakDelIdContext fake delete using id with context
+func (s *Curd) FakDelIdContext(ctx context.Context, table interface{}, id interface{}) (int64, error) {
+	return s.FakDelContext(ctx, table, ideq, id)
 }
 
-// Mod modify using map[string]interface{}
-func (s *Curd) Mod(update map[string]interface{}, table interface{}, where string, args ...interface{}) (int64, error) {
+// Mod modify using map[string]interface{}, where is a sql condition string with args, a Condition, or a map[string]interface{} of columns equal to their values
+// a Condition or map always true, such as an empty map, is refused, an empty string where affects all rows
+func (s *Curd) Mod(update map[string]interface{}, table interface{}, where interface{}, args ...interface{}) (int64, error) {
+	return s.ModContext(context.Background(), update, table, where, args...)
+}
+
+// ModContext modify using map[string]interface{} with context
+func (s *Curd) ModContext(ctx context.Context, update map[string]interface{}, table interface{}, where interface{}, args ...interface{}) (int64, error) {
 	tab := s.table(table)
 	if tab == "" {
 		return 0, errors.New("please set table name first")
 	}
+	cond, args, err := filterArgs(where, args)
+	if err != nil {
+		return 0, err
+	}
+	if s.Version != "" {
+		return s.modVersion(ctx, update, tab, cond, args...)
+	}
 	if s.ModAt != nil {
 		update = s.addAt(update, s.ModAt)
 	}
 	key, val := ModifyPrepareArgs(update)
 	prepare := ""
-	if where == "" {
+	if cond == "" {
 		prepare = fmt.Sprintf("UPDATE %s SET %s;", Identifier(tab), key)
 	} else {
-		prepare = fmt.Sprintf("UPDATE %s SET %s WHERE ( %s );", Identifier(tab), key, where)
+		prepare = fmt.Sprintf("UPDATE %s SET %s WHERE ( %s );", Identifier(tab), key, cond)
 		val = append(val, args...)
 	}
-	return s.Execute(prepare, val...)
+	return s.ExecuteContext(ctx, prepare, val...)
+}
+
+// modVersion modify with optimistic locking, the current version is taken from update
+func (s *Curd) modVersion(ctx context.Context, update map[string]interface{}, tab string, where string, args ...interface{}) (int64, error) {
+	version, ok := update[s.Version]
+	if !ok || version == nil {
+		return 0, fmt.Errorf("please set the current value of version column %s first", s.Version)
+	}
+	// copy the update, the version column is set by increasing
+	tmp := make(map[string]interface{}, len(update))
+	for key, val := range update {
+		if key != s.Version {
+			tmp[key] = val
+		}
+	}
+	if s.ModAt != nil {
+		tmp = s.addAt(tmp, s.ModAt)
+	}
+	column := Identifier(s.Version)
+	key, val := ModifyPrepareArgs(tmp)
+	if key != "" {
+		key += ", "
+	}
+	key += fmt.Sprintf("%s = %s + 1", column, column)
+	prepare := ""
+	if where == "" {
+		prepare = fmt.Sprintf("UPDATE %s SET %s WHERE ( %s = ? );", Identifier(tab), key, column)
+	} else {
+		prepare = fmt.Sprintf("UPDATE %s SET %s WHERE ( %s ) AND ( %s = ? );", Identifier(tab), key, where, column)
+		val = append(val, args...)
+	}
+	val = append(val, version)
+	rows, err := s.ExecuteContext(ctx, prepare, val...)
+	if err != nil {
+		return rows, err
+	}
+	if rows == 0 {
+		return 0, ErrVersionConflict
+	}
+	return rows, nil
 }
 
 // ModId modify using map[string]interface{}
 func (s *Curd) ModId(modify map[string]interface{}, table interface{}, id interface{}) (int64, error) {
-	return s.Mod(modify, table, ideq, id)
+	return s.ModIdContext(context.Background(), modify, table, id)
+}
+
+// ModIdContext modify using map[string]interface{} with context
+func (s *Curd) ModIdContext(ctx context.Context, modify map[string]interface{}, table interface{}, id interface{}) (int64, error) {
+	return s.ModContext(ctx, modify, table, ideq, id)
 }
 
 // ModCtr update contrast, compare the values of before and after, the type of before or after should be AnyStruct, *AnyStruct, map[string]interface{}
-func (s *Curd) ModCtr(before interface{}, after interface{}, table interface{}, where string, args ...interface{}) (int64, error) {
+func (s *Curd) ModCtr(before interface{}, after interface{}, table interface{}, where interface{}, args ...interface{}) (int64, error) {
+	return s.ModCtrContext(context.Background(), before, after, table, where, args...)
+}
+
+// ModCtrContext update contrast with context, compare the values of before and after, the type of before or after should be AnyStruct, *AnyStruct, map[string]interface{}
+func (s *Curd) ModCtrContext(ctx context.Context, before interface{}, after interface{}, table interface{}, where interface{}, args ...interface{}) (int64, error) {
 	var err error
 	b, ok := before.(map[string]interface{})
 	if !ok {
@@ -339,7 +549,14 @@ func (s *Curd) ModCtr(before interface{}, after interface{}, table interface{},
 		}
 	}
 	mod := map[string]interface{}{}
+	if s.Version != "" {
+		// the version of before is the current version, the version of after is ignored
+		mod[s.Version] = b[s.Version]
+	}
 	for key, val := range a {
+		if s.Version != "" && key == s.Version {
+			continue
+		}
 		beforeVal, ok := b[key]
 		if !ok {
 			continue
@@ -349,30 +566,55 @@ func (s *Curd) ModCtr(before interface{}, after interface{}, table interface{},
 		}
 		mod[key] = val
 	}
-	return s.Mod(mod, table, where, args...)
+	return s.ModContext(ctx, mod, table, where, args...)
 }
 
 // ModCtrId update contrast, compare the values of before and after
 func (s *Curd) ModCtrId(before interface{}, after interface{}, table interface{}, id interface{}) (int64, error) {
-	return s.ModCtr(before, after, table, ideq, id)
+	return s.ModCtrIdContext(context.Background(), before, after, table, id)
+}
+
+// ModCtrIdContext update contrast with context, compare the values of before and after
+func (s *Curd) ModCtrIdContext(ctx context.Context, before interface{}, after interface{}, table interface{}, id interface{}) (int64, error) {
+	return s.ModCtrContext(ctx, before, after, table, ideq, id)
 }
 
 // Count sql count rows
 func (s *Curd) Count(prepare string, args ...interface{}) (int64, error) {
-	return s.hat.Count(prepare, args...)
+	return s.CountContext(context.Background(), prepare, args...)
+}
+
+// CountContext sql count rows with context
+func (s *Curd) CountContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.hat.CountContext(ctx, prepare, args...)
 }
 
 // SumInt sql sum int
 func (s *Curd) SumInt(prepare string, args ...interface{}) (int64, error) {
-	return s.hat.SumInt(prepare, args...)
+	return s.SumIntContext(context.Background(), prepare, args...)
+}
+
+// SumIntContext sql sum int with context
+func (s *Curd) SumIntContext(ctx context.Context, prepare string, args ...interface{}) (int64, error) {
+	return s.hat.SumIntContext(ctx, prepare, args...)
 }
 
 // SumFloat sql sum float
 func (s *Curd) SumFloat(prepare string, args ...interface{}) (float64, error) {
-	return s.hat.SumFloat(prepare, args...)
+	return s.SumFloatContext(context.Background(), prepare, args...)
+}
+
+// SumFloatContext sql sum float with context
+func (s *Curd) SumFloatContext(ctx context.Context, prepare string, args ...interface{}) (float64, error) {
+	return s.hat.SumFloatContext(ctx, prepare, args...)
 }
 
 // Exists sql data exists
 func (s *Curd) Exists(prepare string, args ...interface{}) (bool, error) {
-	return s.hat.Exists(prepare, args...)
+	return s.ExistsContext(context.Background(), prepare, args...)
+}
+
+// ExistsContext sql data exists with context
+func (s *Curd) ExistsContext(ctx context.Context, prepare string, args ...interface{}) (bool, error) {
+	return s.hat.ExistsContext(ctx, prepare, args...)
 }
@@ -0,0 +1,82 @@
+package gomysql
+
+import (
+	"errors"
+)
+
+// txHooks callbacks registered on one transaction level, the outermost transaction or a savepoint
+type txHooks struct {
+	commit   []func() // executed in order after the outermost transaction is committed
+	rollback []func() // executed in order after the transaction level is rolled back
+}
+
+// merge append the callbacks of child, used when the savepoint of child is released
+func (s *txHooks) merge(child *txHooks) {
+	s.commit = append(s.commit, child.commit...)
+	s.rollback = append(s.rollback, child.rollback...)
+}
+
+// OnCommit register callback executed after the outermost transaction is committed successfully
+// the callback is discarded if the current transaction level, or any level containing it, is rolled back
+func (s *Hat) OnCommit(callback func()) error {
+	if len(s.session.hooks) == 0 {
+		return errors.New("please start a transaction before registering commit callback")
+	}
+	level := s.session.hooks[len(s.session.hooks)-1]
+	level.commit = append(level.commit, callback)
+	return nil
+}
+
+// OnRollback register callback executed after the current transaction level is rolled back
+// the callback is executed when the savepoint is rolled back, or when the transaction containing it is rolled back or fails to commit
+func (s *Hat) OnRollback(callback func()) error {
+	if len(s.session.hooks) == 0 {
+		return errors.New("please start a transaction before registering rollback callback")
+	}
+	level := s.session.hooks[len(s.session.hooks)-1]
+	level.rollback = append(level.rollback, callback)
+	return nil
+}
+
+// hooksBegin a transaction level started
+func (s *Hat) hooksBegin() {
+	s.session.hooks = append(s.session.hooks, &txHooks{})
+}
+
+// hooksPop remove the innermost transaction level, merge its callbacks into the parent level if merge is true
+func (s *Hat) hooksPop(merge bool) (level *txHooks) {
+	length := len(s.session.hooks)
+	if length == 0 {
+		return &txHooks{}
+	}
+	level = s.session.hooks[length-1]
+	s.session.hooks = s.session.hooks[:length-1]
+	if merge && length > 1 {
+		s.session.hooks[length-2].merge(level)
+	}
+	return
+}
+
+// hooksUnwind discard the levels created after level, their callbacks belong to level from now on
+func (s *Hat) hooksUnwind(level int) {
+	for len(s.session.hooks) > level+1 {
+		s.hooksPop(true)
+	}
+}
+
+// OnCommit register callback executed after the outermost transaction is committed successfully
+func (s *Curd) OnCommit(callback func()) error {
+	return s.hat.OnCommit(callback)
+}
+
+// OnRollback register callback executed after the current transaction level is rolled back
+func (s *Curd) OnRollback(callback func()) error {
+	return s.hat.OnRollback(callback)
+}
+
+// runHooks execute callbacks in order
+func runHooks(callbacks []func()) {
+	for _, callback := range callbacks {
+		callback()
+	}
+}
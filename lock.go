@@ -0,0 +1,122 @@
+package gomysql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// LockMode locking read mode of the SELECT statement, combine ForUpdate or ForShare with Nowait or SkipLocked
+type LockMode int
+
+const (
+	// ForUpdate SELECT ... FOR UPDATE, lock the rows read as if they were updated
+	ForUpdate LockMode = 1 << iota
+	// ForShare SELECT ... FOR SHARE, lock the rows read in share mode, others can read but can not modify them
+	ForShare
+	// Nowait NOWAIT, the statement fails immediately instead of waiting if a row is locked by others
+	Nowait
+	// SkipLocked SKIP LOCKED, the rows locked by others are skipped, the result may be inconsistent
+	SkipLocked
+)
+
+// String locking clause of the mode
+func (s LockMode) String() string {
+	var clause []string
+	switch {
+	case s&ForUpdate != 0:
+		clause = append(clause, "FOR UPDATE")
+	case s&ForShare != 0:
+		clause = append(clause, "FOR SHARE")
+	}
+	switch {
+	case s&Nowait != 0:
+		clause = append(clause, "NOWAIT")
+	case s&SkipLocked != 0:
+		clause = append(clause, "SKIP LOCKED")
+	}
+	return strings.Join(clause, " ")
+}
+
+// validate the mode must be exactly one of ForUpdate and ForShare, with at most one of Nowait and SkipLocked
+func (s LockMode) validate() error {
+	if s&^(ForUpdate|ForShare|Nowait|SkipLocked) != 0 ||
+		s&(ForUpdate|ForShare) == 0 ||
+		s&(ForUpdate|ForShare) == ForUpdate|ForShare ||
+		s&(Nowait|SkipLocked) == Nowait|SkipLocked {
+		return fmt.Errorf("invalid lock mode %d", int(s))
+	}
+	return nil
+}
+
+// clause insert the locking clause of the mode after the last token of the SELECT statement, before the trailing semicolon and comments
+func (s LockMode) clause(prepare string) string {
+	end := 0
+	scan(prepare, func(start int, stop int, comment bool) {
+		if !comment {
+			end = stop
+		}
+	}, func(i int) int {
+		if prepare[i] != ';' && prepare[i] > ' ' {
+			end = i + 1
+		}
+		return 1
+	})
+	return fmt.Sprintf("%s %s%s", prepare[:end], s.String(), prepare[end:])
+}
+
+// ErrLockOutsideTx locking reads are only allowed in a transaction, the locks are released as soon as the statement ends in autocommit mode
+var ErrLockOutsideTx = errors.New("locking read outside transaction")
+
+// ErrLockNowait the rows are locked by others and the locking read with NOWAIT does not wait, use errors.Is(err, ErrLockNowait) to check it
+var ErrLockNowait = errors.New("lock could not be acquired immediately and NOWAIT is set")
+
+// LockError the locking read could not acquire the locks of rows
+type LockError struct {
+	Mode    LockMode // lock mode of the statement
+	Prepare string   // sql statement
+	Err     error    // error returned by the driver
+}
+
+// Error error message
+func (s *LockError) Error() string {
+	return fmt.Sprintf("%s %s: %v", ErrLockNowait.Error(), s.Mode, s.Err)
+}
+
+// Unwrap get the error returned by the driver
+func (s *LockError) Unwrap() error {
+	return s.Err
+}
+
+// Is the error is ErrLockNowait
+func (s *LockError) Is(target error) bool {
+	return target == ErrLockNowait
+}
+
+// lockError convert the error of a locking read that could not acquire the locks under NOWAIT into *LockError
+func lockError(mode LockMode, prepare string, err error) error {
+	// 3572: ER_LOCK_NOWAIT, statement aborted because lock(s) could not be acquired immediately and NOWAIT is set
+	if me, ok := err.(*mysql.MySQLError); ok && me.Number == 3572 {
+		return &LockError{
+			Mode:    mode,
+			Prepare: prepare,
+			Err:     err,
+		}
+	}
+	return err
+}
+
+// Lock get a copy of hat executing read statements with the lock mode, such as ForUpdate, ForShare|Nowait, ForUpdate|SkipLocked
+// the statement is refused with ErrLockOutsideTx if hat is not in a transaction
+func (s *Hat) Lock(mode LockMode) *Hat {
+	tmp := s.Clone()
+	tmp.lock = mode
+	return tmp
+}
+
+// Lock get a copy of curd executing read statements with the lock mode, such as ForUpdate, ForShare|Nowait, ForUpdate|SkipLocked
+func (s *Curd) Lock(mode LockMode) *Curd {
+	return s.clone(s.hat.Lock(mode))
+}
@@ -0,0 +1,28 @@
+package gomysql
+
+import (
+	"testing"
+)
+
+func TestLockModeClause(t *testing.T) {
+	tests := []struct {
+		prepare string
+		want    string
+	}{
+		{"SELECT * FROM `t`", "SELECT * FROM `t` FOR UPDATE"},
+		{"SELECT * FROM `t`;", "SELECT * FROM `t` FOR UPDATE;"},
+		{"SELECT * FROM `t` ; \n", "SELECT * FROM `t` FOR UPDATE ; \n"},
+		{"SELECT * FROM `t` -- c", "SELECT * FROM `t` FOR UPDATE -- c"},
+		{"SELECT * FROM `t`; # c", "SELECT * FROM `t` FOR UPDATE; # c"},
+		{"SELECT * FROM `t` /* c */", "SELECT * FROM `t` FOR UPDATE /* c */"},
+		{"SELECT * FROM `t` -- c\n;", "SELECT * FROM `t` FOR UPDATE -- c\n;"},
+		{"SELECT * FROM `t` WHERE `name` = 'a -- b'", "SELECT * FROM `t` WHERE `name` = 'a -- b' FOR UPDATE"},
+		{"SELECT * FROM `t` WHERE `name` = 'x' -- c", "SELECT * FROM `t` WHERE `name` = 'x' FOR UPDATE -- c"},
+		{"SELECT * FROM `t -- c`", "SELECT * FROM `t -- c` FOR UPDATE"},
+	}
+	for _, test := range tests {
+		if got := ForUpdate.clause(test.prepare); got != test.want {
+			t.Errorf("clause(%q) = %q, want %q", test.prepare, got, test.want)
+		}
+	}
+}
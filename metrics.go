@@ -0,0 +1,190 @@
+package gomysql
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	KindQuery    = "query"    // query statement, such as SELECT
+	KindExec     = "exec"     // non-query statement, such as INSERT, UPDATE, DELETE
+	KindBegin    = "begin"    // start transaction
+	KindCommit   = "commit"   // commit transaction
+	KindRollback = "rollback" // rollback transaction
+)
+
+// metric counter families
+const (
+	metricStatements   = "gomysql_statements_total"
+	metricErrors       = "gomysql_statement_errors_total"
+	metricRows         = "gomysql_rows_returned_total"
+	metricTransactions = "gomysql_transactions_total"
+)
+
+// metricHelp help text of counter families
+var metricHelp = map[string]string{
+	metricStatements:   "Number of statements executed, by connection and statement kind.",
+	metricErrors:       "Number of statements and transaction operations that returned an error, by connection and kind.",
+	metricRows:         "Number of rows returned by GetFirst, GetAll, JsonFirst, JsonAll and their byte variants, by connection.",
+	metricTransactions: "Number of transactions finished, by connection and result.",
+}
+
+// metricKey counter identity
+type metricKey struct {
+	metric     string
+	connection string
+	label      string // value of the kind or result label, empty if the metric has no such label
+}
+
+// counters statement counters, metricKey => *uint64
+var counters sync.Map
+
+// count add n to the counter
+func count(metric string, connection string, label string, n uint64) {
+	key := metricKey{metric: metric, connection: connection, label: label}
+	tmp, ok := counters.Load(key)
+	if !ok {
+		tmp, _ = counters.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(tmp.(*uint64), n)
+}
+
+// observe count the statement of kind executed on the connection named name
+func observe(name string, kind string, err error) {
+	if kind == KindQuery || kind == KindExec {
+		count(metricStatements, name, kind, 1)
+	}
+	if err != nil {
+		count(metricErrors, name, kind, 1)
+		return
+	}
+	switch kind {
+	case KindCommit:
+		count(metricTransactions, name, "commit", 1)
+	case KindRollback:
+		count(metricTransactions, name, "rollback", 1)
+	}
+}
+
+// observeRows count the rows returned on the connection named name
+func observeRows(name string, rows int) {
+	if rows > 0 {
+		count(metricRows, name, "", uint64(rows))
+	}
+}
+
+// metricLabel escape the label value
+func metricLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
+
+// poolStat gauge or counter of sql.DBStats
+type poolStat struct {
+	name  string
+	kind  string
+	help  string
+	value func(stats sql.DBStats) float64
+}
+
+// poolStats exported fields of sql.DBStats
+var poolStats = []poolStat{
+	{"gomysql_pool_max_open_connections", "gauge", "Maximum number of open connections to the database.", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"gomysql_pool_open_connections", "gauge", "The number of established connections both in use and idle.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"gomysql_pool_in_use_connections", "gauge", "The number of connections currently in use.", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"gomysql_pool_idle_connections", "gauge", "The number of idle connections.", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"gomysql_pool_wait_count_total", "counter", "The total number of connections waited for.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"gomysql_pool_wait_duration_seconds_total", "counter", "The total time blocked waiting for a new connection.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"gomysql_pool_max_idle_closed_total", "counter", "The total number of connections closed due to SetMaxIdleConns.", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"gomysql_pool_max_idle_time_closed_total", "counter", "The total number of connections closed due to SetConnMaxIdleTime.", func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{"gomysql_pool_max_lifetime_closed_total", "counter", "The total number of connections closed due to SetConnMaxLifetime.", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+// poolInstance database connect object of a registered connection, instance is primary or the replica name
+type poolInstance struct {
+	connection string
+	instance   string
+	db         *sql.DB
+}
+
+// poolInstances all registered database connect objects in order
+func poolInstances() (instances []poolInstance) {
+	for _, name := range Names() {
+		if cluster := GetCluster(name); cluster != nil {
+			instances = append(instances, poolInstance{connection: name, instance: "primary", db: cluster.Primary()})
+			for _, replica := range cluster.Replicas() {
+				if replica.Db != nil {
+					instances = append(instances, poolInstance{connection: name, instance: replica.Name, db: replica.Db})
+				}
+			}
+			continue
+		}
+		if database := GetName(name); database != nil {
+			instances = append(instances, poolInstance{connection: name, instance: "primary", db: database})
+		}
+	}
+	return
+}
+
+// metricConnection the value of the label connection of the connection registered under name
+func metricConnection(name string) string {
+	if name == DefaultName {
+		return DefaultLabel
+	}
+	return name
+}
+
+// WriteMetrics write the connection pool statistics and statement counters in the prometheus text exposition format
+// the label connection is the registered name, the default connection is connection="(default)", see DefaultLabel
+func WriteMetrics(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	instances := poolInstances()
+	stats := make([]sql.DBStats, len(instances))
+	for key, val := range instances {
+		stats[key] = val.db.Stats()
+	}
+	for _, stat := range poolStats {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", stat.name, stat.help, stat.name, stat.kind)
+		for key, val := range instances {
+			fmt.Fprintf(buf, "%s{connection=\"%s\",instance=\"%s\"} %v\n", stat.name, metricLabel(metricConnection(val.connection)), metricLabel(val.instance), stat.value(stats[key]))
+		}
+	}
+	families := map[string][]string{}
+	counters.Range(func(key, value interface{}) bool {
+		k := key.(metricKey)
+		labels := fmt.Sprintf("connection=\"%s\"", metricLabel(metricConnection(k.connection)))
+		switch k.metric {
+		case metricStatements, metricErrors:
+			labels += fmt.Sprintf(",kind=\"%s\"", metricLabel(k.label))
+		case metricTransactions:
+			labels += fmt.Sprintf(",result=\"%s\"", metricLabel(k.label))
+		}
+		families[k.metric] = append(families[k.metric], fmt.Sprintf("%s{%s} %d", k.metric, labels, atomic.LoadUint64(value.(*uint64))))
+		return true
+	})
+	for _, metric := range []string{metricStatements, metricErrors, metricRows, metricTransactions} {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", metric, metricHelp[metric], metric)
+		lines := families[metric]
+		sort.Strings(lines)
+		for _, line := range lines {
+			fmt.Fprintln(buf, line)
+		}
+	}
+	return buf.Flush()
+}
+
+// MetricsHandler http handler that exposes the metrics in the prometheus text exposition format
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteMetrics(w)
+	})
+}
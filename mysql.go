@@ -2,12 +2,14 @@ package gomysql
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/gob"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -18,39 +20,39 @@ const (
 	Backtick = "`" // backtick
 )
 
-// db database connect object
-var db *sql.DB
-
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
 // Open connect to mysql service, auto set database connect
 // dn: driver name, dsn: data source name
 // username:password@tcp(host:port)/test?charset=utf8mb4&collation=utf8mb4_unicode_ci
-func Open(dn string, dsn string) (err error) {
-	db, err = sql.Open(dn, dsn)
-	if err != nil {
-		return
-	}
-	db.SetConnMaxLifetime(time.Minute * 3)
-	db.SetMaxOpenConns(512)
-	db.SetMaxIdleConns(128)
-	return
+func Open(dn string, dsn string) error {
+	return OpenName(DefaultName, dn, dsn)
 }
 
 // Db0 set database connect object
 func Db0(database *sql.DB) {
-	db = database
+	SetName(DefaultName, database)
 }
 
 // Db1 get database connect object
 func Db1() *sql.DB {
-	return db
+	return GetName(DefaultName)
 }
 
-// Db2 database curd object
-func Db2() *Hat {
+// Db2 database curd object, use the connection registered under name if a name is given, otherwise the default connection
+func Db2(name ...string) *Hat {
+	tmp := DefaultName
+	length := len(name)
+	if length > 0 {
+		tmp = name[length-1]
+	}
+	database := GetName(tmp)
 	return &Hat{
-		db: db,
+		name:    tmp,
+		db:      database,
+		cluster: GetCluster(tmp),
+		gate:    gateOf(database),
+		session: &session{},
 	}
 }
 
@@ -111,206 +113,551 @@ func GobTransfer(source interface{}, result interface{}) error {
 	return gob.NewDecoder(bytes.NewReader(buffer.Bytes())).Decode(result)
 }
 
-// Identifier MySql identifier
+// Identifier MySql identifier, such as db.table => `db`.`table`, alias.column => `alias`.`column`, alias.* => `alias`.*
+// the alias after AS is quoted too, such as table AS t => `table` AS `t`, COUNT(*) AS total => COUNT(*) AS `total`
 func Identifier(s string) string {
+	if expression, alias, ok := identifierAlias(s, false); ok {
+		return fmt.Sprintf("%s AS %s", Identifier(expression), Identifier(alias))
+	}
 	if strings.Contains(s, "(") {
 		// there is an identifier for a function call, do nothing
 		return s
 	}
 	s = strings.ReplaceAll(s, Backtick, "")
+	if strings.HasSuffix(s, ".*") {
+		return fmt.Sprintf("%s.*", Identifier(strings.TrimSuffix(s, ".*")))
+	}
 	s = strings.ReplaceAll(s, ".", fmt.Sprintf("%s.%s", Backtick, Backtick))
 	s = fmt.Sprintf("%s%s%s", Backtick, s, Backtick)
 	return s
 }
 
-// Query execute query sql
-func Query(scan func(rows *sql.Rows) (err error), prepare string, args ...interface{}) error {
-	return Db2().Scan(scan).Prepare(prepare).Args(args...).Query()
-}
-
-// Execute execute non-query sql
-func Execute(prepare string, args ...interface{}) (int64, error) {
-	return Db2().Prepare(prepare).Args(args...).Execute()
+// identifierAlias split `expression AS alias` into expression and alias, the alias is a name optionally quoted by backticks
+// implicit allows `table alias` without AS after a name only, such as order o
+func identifierAlias(s string, implicit bool) (expression string, alias string, ok bool) {
+	s = strings.TrimSpace(s)
+	index := strings.LastIndexAny(s, " \t\r\n")
+	if index < 0 {
+		return
+	}
+	expression, alias = strings.TrimRight(s[:index], " \t\r\n"), s[index+1:]
+	name := alias
+	if strings.HasPrefix(name, Backtick) || strings.HasSuffix(name, Backtick) {
+		if len(name) < 2 || !strings.HasPrefix(name, Backtick) || !strings.HasSuffix(name, Backtick) {
+			return
+		}
+		name = name[1 : len(name)-1]
+	}
+	// the space is inside a quoted identifier, such as `my column`
+	if name == "" || strings.Count(expression, Backtick)%2 != 0 {
+		return
+	}
+	for i := 0; i < len(name); i++ {
+		if !word(name[i]) {
+			return
+		}
+	}
+	upper := strings.ToUpper(expression)
+	if length := len(upper); length > 2 && strings.HasSuffix(upper, "AS") && strings.ContainsAny(upper[length-3:length-2], " \t\r\n") {
+		expression = strings.TrimSpace(expression[:length-2])
+		ok = expression != ""
+		return
+	}
+	ok = implicit && expression != "" && !strings.ContainsAny(expression, " \t\r\n()")
+	return
 }
 
-// Transaction transaction execution, automatic rollback on error
-func Transaction(closure func(hat *Hat) (err error)) error {
-	return Db2().Transaction(closure)
+// Hat mysql database sql statement execute object
+// Prepare, Args, Scan, Timeout, Lock, Primary, Replica and SetTimeout return a copy of hat holding the statement, so a hat can be shared by goroutines
+// the copies share the database session of hat, Begin starts a new session of the hat it is called on, the copies made afterwards share its transaction
+// Begin changes the hat, so call it on a hat owned by one goroutine such as Db2() or Clone(), or use Transaction to run a transaction on its own session
+type Hat struct {
+	name    string                           // name of the registered database connection
+	db      *sql.DB                          // database connection object
+	gate    *gate                            // in-flight work of the database connection object
+	cluster *Cluster                         // cluster of the database connection, read statements are routed to its replicas
+	primary bool                             // execute read statements on the primary
+	timeout time.Duration                    // default timeout of every statement, 0 means no timeout
+	session *session                         // database session shared by hat and its copies
+	once    time.Duration                    // timeout of the statement, overrides the default timeout
+	lock    LockMode                         // lock mode of the read statement
+	prepare string                           // sql statement to be executed
+	args    []interface{}                    // executed sql parameters
+	scan    func(rows *sql.Rows) (err error) // scan query results
+	err     error                            // error of building the statement, returned when the statement is executed
 }
 
-// Create execute insert sql
-func Create(prepare string, args ...interface{}) (int64, error) {
-	return Db2().Prepare(prepare).Args(args...).Create()
+// session database session shared by a hat and its copies, the pinned connection, the transaction and the last executed statement
+type session struct {
+	conn       *sql.Conn     // pinned connection, the statements are executed on it instead of db if it is set
+	tx         *sql.Tx       // database transaction object
+	savepoints []string      // savepoints of nested transactions, the innermost is the last
+	hooks      []*txHooks    // commit and rollback callbacks of every transaction level, the outermost is the first
+	mutex      sync.Mutex    // protects the last executed statement
+	prepare    string        // last executed sql statement
+	args       []interface{} // parameters of the last executed sql statement
 }
 
-// Count sql count rows
-func Count(prepare string, args ...interface{}) (int64, error) {
-	return Db2().Count(prepare, args...)
+// executed record the last executed statement
+func (s *session) executed(prepare string, args []interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prepare, s.args = prepare, args
 }
 
-// SumInt sql sum int
-func SumInt(prepare string, args ...interface{}) (int64, error) {
-	return Db2().SumInt(prepare, args...)
+// last get the last executed statement
+func (s *session) last() (string, []interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.prepare, s.args
 }
 
-// SumFloat sql sum float
-func SumFloat(prepare string, args ...interface{}) (float64, error) {
-	return Db2().SumFloat(prepare, args...)
+// Begin start a transaction
+func (s *Hat) Begin() error {
+	return s.BeginContext(context.Background())
 }
 
-// Exists sql data exists
-func Exists(prepare string, args ...interface{}) (bool, error) {
-	return Db2().Exists(prepare, args...)
+// BeginContext start a transaction, the transaction is rolled back if ctx is done before it is committed
+// when a transaction has already started, create a savepoint instead, the matching Rollback or Commit rolls back to or releases the savepoint
+func (s *Hat) BeginContext(ctx context.Context) error {
+	return s.BeginTx(ctx, nil)
 }
 
-// JsonFirst fetch first one using json
-func JsonFirst(fetch interface{}, prepare string, args ...interface{}) (empty bool, err error) {
-	empty, err = Db2().Prepare(prepare).Args(args...).JsonFirst(fetch)
+// BeginTx start a transaction with options such as isolation level and read only, nil options use the default of the server
+// when a transaction has already started, create a savepoint instead and ignore the options
+// the transaction is started on a new session of hat, do not call it on a hat shared by goroutines, use Transaction instead
+func (s *Hat) BeginTx(ctx context.Context, opts *sql.TxOptions) (err error) {
+	if s.session.tx != nil {
+		name := fmt.Sprintf("gomysql_savepoint_%d", len(s.session.savepoints)+1)
+		err = s.txExec(ctx, "SAVEPOINT "+Identifier(name))
+		if err != nil {
+			return
+		}
+		s.session.savepoints = append(s.session.savepoints, name)
+		s.hooksBegin()
+		return
+	}
+	if s.db == nil {
+		err = s.unregistered()
+		return
+	}
+	var started func(tx *sql.Tx)
+	ctx, started, err = s.gate.begin(ctx)
+	if err != nil {
+		return
+	}
+	// the transaction starts on a new session, the copies of hat made before are not pulled into it
+	s.session = &session{conn: s.session.conn}
+	if s.session.conn != nil {
+		s.session.tx, err = s.session.conn.BeginTx(ctx, opts)
+	} else {
+		s.session.tx, err = s.db.BeginTx(ctx, opts)
+	}
+	if err != nil {
+		s.session.tx = nil
+		started(nil)
+		observe(s.name, KindBegin, err)
+		return
+	}
+	started(s.session.tx)
+	s.session.hooks = nil
+	s.hooksBegin()
 	return
 }
 
-// JsonAll fetch all using json
-func JsonAll(fetch interface{}, prepare string, args ...interface{}) error {
-	return Db2().Prepare(prepare).Args(args...).JsonAll(fetch)
+// BeginSnapshot start a transaction using START TRANSACTION WITH CONSISTENT SNAPSHOT, all reads of the transaction see the same snapshot
+// when a transaction has already started, create a savepoint instead
+func (s *Hat) BeginSnapshot(ctx context.Context, readOnly bool) (err error) {
+	if s.session.tx != nil {
+		return s.BeginTx(ctx, nil)
+	}
+	err = s.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	statement := "START TRANSACTION WITH CONSISTENT SNAPSHOT"
+	if readOnly {
+		statement += ", READ ONLY"
+	}
+	// the transaction started by BeginTx is empty, it is committed implicitly and replaced by the snapshot transaction on the same connection
+	err = s.txExec(ctx, statement)
+	if err != nil {
+		_ = s.Rollback()
+	}
+	return
 }
 
-// GetFirst get first one
-func GetFirst(prepare string, args ...interface{}) (map[string]interface{}, error) {
-	return Db2().Prepare(prepare).Args(args...).GetFirst()
+// Rollback transaction rollback, roll back to the savepoint if the transaction is nested
+func (s *Hat) Rollback() (err error) {
+	if length := len(s.session.savepoints); length > 0 {
+		name := s.session.savepoints[length-1]
+		s.session.savepoints = s.session.savepoints[:length-1]
+		err = s.txExec(context.Background(), "ROLLBACK TO SAVEPOINT "+Identifier(name))
+		// the rollback callbacks wait for the parent level if rolling back to the savepoint failed
+		level := s.hooksPop(err != nil)
+		if err == nil {
+			runHooks(level.rollback)
+		}
+		return
+	}
+	if s.session.tx != nil {
+		err = s.session.tx.Rollback()
+		observe(s.name, KindRollback, err)
+		s.gate.end(s.session.tx)
+		s.session.tx = nil
+		level := s.hooksPop(false)
+		runHooks(level.rollback)
+	}
+	return
 }
 
-// GetAll get all
-func GetAll(prepare string, args ...interface{}) ([]map[string]interface{}, error) {
-	return Db2().Prepare(prepare).Args(args...).GetAll()
+// Commit transaction commit, release the savepoint if the transaction is nested, the outermost commit commits everything
+func (s *Hat) Commit() (err error) {
+	if length := len(s.session.savepoints); length > 0 {
+		name := s.session.savepoints[length-1]
+		s.session.savepoints = s.session.savepoints[:length-1]
+		err = s.txExec(context.Background(), "RELEASE SAVEPOINT "+Identifier(name))
+		s.hooksPop(true)
+		return
+	}
+	if s.session.tx != nil {
+		err = s.session.tx.Commit()
+		observe(s.name, KindCommit, err)
+		s.gate.end(s.session.tx)
+		s.session.tx = nil
+		level := s.hooksPop(false)
+		if err == nil {
+			runHooks(level.commit)
+		} else {
+			runHooks(level.rollback)
+		}
+	}
+	return
 }
 
-// GetFirstByte get first one
-func GetFirstByte(prepare string, args ...interface{}) (map[string][]byte, error) {
-	return Db2().Prepare(prepare).Args(args...).GetFirstByte()
+// txExec execute the transaction control statement, such as SAVEPOINT, in the transaction
+func (s *Hat) txExec(ctx context.Context, statement string) (err error) {
+	_, err = s.session.tx.ExecContext(ctx, statement)
+	observe(s.name, KindExec, err)
+	return
 }
 
-// GetAllByte get all
-func GetAllByte(prepare string, args ...interface{}) ([]map[string][]byte, error) {
-	return Db2().Prepare(prepare).Args(args...).GetAllByte()
+// Transaction closure execute transaction, automatic rollback on error
+func (s *Hat) Transaction(closure func(hat *Hat) (err error)) error {
+	return s.TransactionContext(context.Background(), closure)
 }
 
-// Hat mysql database sql statement execute object
-type Hat struct {
-	db      *sql.DB                          // database connection object
-	tx      *sql.Tx                          // database transaction object
-	prepare string                           // sql statement to be executed
-	args    []interface{}                    // executed sql parameters
-	scan    func(rows *sql.Rows) (err error) // scan query results
+// TransactionContext closure execute transaction with context, automatic rollback on error
+func (s *Hat) TransactionContext(ctx context.Context, closure func(hat *Hat) (err error)) error {
+	return s.TransactionTx(ctx, nil, closure)
 }
 
-// Begin start a transaction
-func (s *Hat) Begin() (err error) {
-	if s.tx != nil {
-		err = errors.New("please commit or rollback the opened transaction")
-		return
-	}
-	s.tx, err = s.db.Begin()
-	return
+// TransactionTx closure execute transaction with options such as isolation level and read only, automatic rollback on error
+func (s *Hat) TransactionTx(ctx context.Context, opts *sql.TxOptions, closure func(hat *Hat) (err error)) error {
+	hat := s.transactional()
+	return hat.transaction(func() error { return hat.BeginTx(ctx, opts) }, closure)
 }
 
-// Rollback transaction rollback
-func (s *Hat) Rollback() (err error) {
-	if s.tx != nil {
-		err = s.tx.Rollback()
-		s.tx = nil
-	}
-	return
+// TransactionSnapshot closure execute transaction started with consistent snapshot, automatic rollback on error
+func (s *Hat) TransactionSnapshot(ctx context.Context, readOnly bool, closure func(hat *Hat) (err error)) error {
+	hat := s.transactional()
+	return hat.transaction(func() error { return hat.BeginSnapshot(ctx, readOnly) }, closure)
 }
 
-// Commit transaction commit
-func (s *Hat) Commit() (err error) {
-	if s.tx != nil {
-		err = s.tx.Commit()
-		s.tx = nil
+// transactional get the hat starting the transaction of closure, a copy of hat on a new session unless hat is in a transaction already
+// so the transaction is not seen by the other goroutines sharing hat, and a nested transaction uses the savepoint of the opened one
+func (s *Hat) transactional() *Hat {
+	if s.session.tx != nil {
+		return s
 	}
-	return
+	tmp := s.Clone()
+	tmp.session = &session{conn: s.session.conn}
+	return tmp
 }
 
-// Transaction closure execute transaction, automatic rollback on error
-func (s *Hat) Transaction(closure func(hat *Hat) (err error)) (err error) {
-	err = s.Begin()
+// transaction start transaction by begin, execute closure, commit if closure succeeded
+// roll back if closure returned an error or panicked, the panic is raised again after rolling back
+// the error of commit is returned, the error of rolling back is returned together with the error of closure by *TxError
+func (s *Hat) transaction(begin func() error, closure func(hat *Hat) (err error)) (err error) {
+	err = begin()
 	if err != nil {
 		return
 	}
+	// savepoints of this transaction level, the savepoints left open by closure are discarded with it
+	level := len(s.session.savepoints)
+	panicked := true
+	defer func() {
+		if !panicked {
+			return
+		}
+		s.unwind(level)
+		_ = s.Rollback()
+	}()
 	err = closure(s)
+	panicked = false
+	s.unwind(level)
 	if err != nil {
-		_ = s.Rollback()
+		if tmp := s.Rollback(); tmp != nil {
+			err = &TxError{Err: err, RollbackErr: tmp}
+		}
 		return
 	}
-	_ = s.Commit()
+	err = s.Commit()
+	return
+}
+
+// unwind discard the savepoints created after level, the next Rollback or Commit applies to the savepoint of level
+func (s *Hat) unwind(level int) {
+	if len(s.session.savepoints) > level {
+		s.session.savepoints = s.session.savepoints[:level]
+	}
+	s.hooksUnwind(level)
+}
+
+// TxError the transaction closure returned an error, and rolling back the transaction failed too
+type TxError struct {
+	Err         error // error returned by the transaction closure
+	RollbackErr error // error of rolling back
+}
+
+// Error error message
+func (s *TxError) Error() string {
+	return fmt.Sprintf("%v; rollback: %v", s.Err, s.RollbackErr)
+}
+
+// Unwrap get the error returned by the transaction closure
+func (s *TxError) Unwrap() error {
+	return s.Err
+}
+
+// Is the error of rolling back is target
+func (s *TxError) Is(target error) bool {
+	return errors.Is(s.RollbackErr, target)
+}
+
+// Primary get a copy of hat executing the read statements on the primary database connection, use it to read your own writes
+func (s *Hat) Primary() *Hat {
+	tmp := s.Clone()
+	tmp.primary = true
+	return tmp
+}
+
+// Replica get a copy of hat executing the read statements on the replicas if the database connection is a cluster
+func (s *Hat) Replica() *Hat {
+	tmp := s.Clone()
+	tmp.primary = false
+	return tmp
+}
+
+// SetTimeout get a copy of hat with the default timeout of every statement, 0 means no timeout
+func (s *Hat) SetTimeout(timeout time.Duration) *Hat {
+	tmp := s.Clone()
+	tmp.timeout = timeout
+	return tmp
+}
+
+// Timeout get a copy of hat executing statements within timeout, overrides the default timeout
+func (s *Hat) Timeout(timeout time.Duration) *Hat {
+	tmp := s.Clone()
+	tmp.once = timeout
+	return tmp
+}
+
+// timeouts get the timeout of the statement to be executed
+func (s *Hat) timeouts() (timeout time.Duration) {
+	timeout = s.timeout
+	if s.once > 0 {
+		timeout = s.once
+	}
 	return
 }
 
-// Scan set scan query result (anonymous function)
+// Clone copy hat, the copy shares the database session of hat and can be changed without affecting hat
+func (s *Hat) Clone() *Hat {
+	tmp := *s
+	return &tmp
+}
+
+// Scan get a copy of hat with the scan query result (anonymous function)
 func (s *Hat) Scan(scan func(rows *sql.Rows) (err error)) *Hat {
-	s.scan = scan
-	return s
+	tmp := s.Clone()
+	tmp.scan = scan
+	return tmp
 }
 
-// Prepare set prepared sql statement
+// Prepare get a copy of hat with the prepared sql statement
 func (s *Hat) Prepare(prepare string) *Hat {
-	s.prepare = prepare
-	return s
+	tmp := s.Clone()
+	tmp.prepare = prepare
+	return tmp
 }
 
-// Args set the parameter list of the prepared sql statement
+// Args get a copy of hat with the parameter list of the prepared sql statement
 func (s *Hat) Args(args ...interface{}) *Hat {
-	s.args = args
-	return s
+	tmp := s.Clone()
+	tmp.args = args
+	return tmp
 }
 
 // PrepareArgs get prepared sql statement and parameter list of prepared sql statement
+// if hat has no prepared sql statement, get the last statement executed on the session of hat
 func (s *Hat) PrepareArgs() (string, []interface{}) {
+	if s.prepare == "" {
+		return s.session.last()
+	}
 	return s.prepare, s.args
 }
 
 // stmt execute the prepared sql statement, if the transaction has already started, use the transaction to execute the prepared sql statement first
-func (s *Hat) stmt() (*sql.Stmt, error) {
-	if s.tx != nil {
-		return s.tx.Prepare(s.prepare)
-	} else {
-		return s.db.Prepare(s.prepare)
+// read statements are executed on a replica of the cluster unless the primary is required
+func (s *Hat) stmt(ctx context.Context, read bool, prepare string) (*sql.Stmt, error) {
+	if s.session.tx != nil {
+		return s.session.tx.PrepareContext(ctx, prepare)
+	}
+	if s.session.conn != nil {
+		return s.session.conn.PrepareContext(ctx, prepare)
 	}
+	if s.db == nil {
+		return nil, s.unregistered()
+	}
+	if read && s.cluster != nil && !s.primary {
+		return s.cluster.reader().PrepareContext(ctx, prepare)
+	}
+	return s.db.PrepareContext(ctx, prepare)
+}
+
+// unregistered the database connection of hat is not registered
+func (s *Hat) unregistered() error {
+	return fmt.Errorf("database connection %q is not registered", s.name)
 }
 
-// stmtQuery stmt query
-func (s *Hat) stmtQuery() (*sql.Rows, error) {
-	stmt, err := s.stmt()
+// stmtQuery stmt query, the named parameters are bound by NamedArgs and the slice parameters are expanded by ExpandArgs
+func (s *Hat) stmtQuery(ctx context.Context, prepare string) (*sql.Rows, error) {
+	prepare, args, err := NamedArgs(prepare, s.args)
+	if err != nil {
+		return nil, err
+	}
+	prepare, args = ExpandArgs(prepare, args)
+	stmt, err := s.stmt(ctx, true, prepare)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	return stmt.Query(s.args...)
+	return stmt.QueryContext(ctx, args...)
 }
 
-// stmtExec stmt exec
-func (s *Hat) stmtExec() (sql.Result, error) {
-	stmt, err := s.stmt()
+// stmtExec stmt exec, the named parameters are bound by NamedArgs and the slice parameters are expanded by ExpandArgs
+func (s *Hat) stmtExec(ctx context.Context) (sql.Result, error) {
+	prepare, args, err := NamedArgs(s.prepare, s.args)
+	if err != nil {
+		return nil, err
+	}
+	prepare, args = ExpandArgs(prepare, args)
+	stmt, err := s.stmt(ctx, false, prepare)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	return stmt.Exec(s.args...)
+	return stmt.ExecContext(ctx, args...)
 }
 
-// Query execute query sql
-func (s *Hat) Query() error {
-	rows, err := s.stmtQuery()
+// enter start a statement, the statements outside transaction are refused when the database connection is shutting down
+func (s *Hat) enter(ctx context.Context) (context.Context, func(), error) {
+	if s.session.tx != nil {
+		return ctx, func() {}, nil
+	}
+	return s.gate.enter(ctx)
+}
+
+// query execute the query sql statement and scan the result, the statement timeout applies until scanning is finished
+func (s *Hat) query(ctx context.Context, scan func(rows *sql.Rows) (err error)) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+	var leave func()
+	ctx, leave, err = s.enter(ctx)
 	if err != nil {
-		return err
+		return
+	}
+	defer leave()
+	prepare := s.prepare
+	timeout := s.timeouts()
+	lock := s.lock
+	if lock != 0 {
+		if err = lock.validate(); err != nil {
+			return
+		}
+		if s.session.tx == nil {
+			err = ErrLockOutsideTx
+			return
+		}
+		prepare = lock.clause(prepare)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		prepare = MaxExecutionTime(prepare, timeout)
+	}
+	s.session.executed(s.prepare, s.args)
+	var rows *sql.Rows
+	rows, err = s.stmtQuery(ctx, prepare)
+	if err == nil {
+		defer rows.Close()
+		err = scan(rows)
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	err = timeoutError(ctx, timeout, s.prepare, err)
+	if lock&Nowait != 0 {
+		err = lockError(lock, s.prepare, err)
+	}
+	observe(s.name, KindQuery, err)
+	return err
+}
+
+// exec execute the non-query sql statement within the statement timeout
+func (s *Hat) exec(ctx context.Context) (sql.Result, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	ctx, leave, err := s.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	timeout := s.timeouts()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
-	defer rows.Close()
-	return s.scan(rows)
+	s.session.executed(s.prepare, s.args)
+	result, err := s.stmtExec(ctx)
+	err = timeoutError(ctx, timeout, s.prepare, err)
+	observe(s.name, KindExec, err)
+	return result, err
+}
+
+// Query execute query sql
+func (s *Hat) Query() error {
+	return s.QueryContext(context.Background())
+}
+
+// QueryContext execute query sql with context
+func (s *Hat) QueryContext(ctx context.Context) error {
+	return s.query(ctx, s.scan)
 }
 
 // Execute execute non-query sql
 func (s *Hat) Execute() (int64, error) {
-	result, err := s.stmtExec()
+	return s.ExecuteContext(context.Background())
+}
+
+// ExecuteContext execute non-query sql with context
+func (s *Hat) ExecuteContext(ctx context.Context) (int64, error) {
+	result, err := s.exec(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -319,7 +666,12 @@ func (s *Hat) Execute() (int64, error) {
 
 // Create execute the insert sql statement and get the self-increasing primary key value
 func (s *Hat) Create() (int64, error) {
-	result, err := s.stmtExec()
+	return s.CreateContext(context.Background())
+}
+
+// CreateContext execute the insert sql statement with context and get the self-increasing primary key value
+func (s *Hat) CreateContext(ctx context.Context) (int64, error) {
+	result, err := s.exec(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -327,18 +679,28 @@ func (s *Hat) Create() (int64, error) {
 }
 
 // Count sql count rows
-func (s *Hat) Count(prepare string, args ...interface{}) (count int64, err error) {
+func (s *Hat) Count(prepare string, args ...interface{}) (int64, error) {
+	return s.CountContext(context.Background(), prepare, args...)
+}
+
+// CountContext sql count rows with context
+func (s *Hat) CountContext(ctx context.Context, prepare string, args ...interface{}) (count int64, err error) {
 	err = s.Scan(func(rows *sql.Rows) (err error) {
 		if rows.Next() {
 			err = rows.Scan(&count)
 		}
 		return
-	}).Prepare(prepare).Args(args...).Query()
+	}).Prepare(prepare).Args(args...).QueryContext(ctx)
 	return
 }
 
 // SumInt sql sum int
-func (s *Hat) SumInt(prepare string, args ...interface{}) (sum int64, err error) {
+func (s *Hat) SumInt(prepare string, args ...interface{}) (int64, error) {
+	return s.SumIntContext(context.Background(), prepare, args...)
+}
+
+// SumIntContext sql sum int with context
+func (s *Hat) SumIntContext(ctx context.Context, prepare string, args ...interface{}) (sum int64, err error) {
 	err = s.Scan(func(rows *sql.Rows) (err error) {
 		if rows.Next() {
 			var tmp *int64
@@ -351,12 +713,17 @@ func (s *Hat) SumInt(prepare string, args ...interface{}) (sum int64, err error)
 			}
 		}
 		return
-	}).Prepare(prepare).Args(args...).Query()
+	}).Prepare(prepare).Args(args...).QueryContext(ctx)
 	return
 }
 
 // SumFloat sql sum float
-func (s *Hat) SumFloat(prepare string, args ...interface{}) (sum float64, err error) {
+func (s *Hat) SumFloat(prepare string, args ...interface{}) (float64, error) {
+	return s.SumFloatContext(context.Background(), prepare, args...)
+}
+
+// SumFloatContext sql sum float with context
+func (s *Hat) SumFloatContext(ctx context.Context, prepare string, args ...interface{}) (sum float64, err error) {
 	err = s.Scan(func(rows *sql.Rows) (err error) {
 		if rows.Next() {
 			var tmp *float64
@@ -369,31 +736,38 @@ func (s *Hat) SumFloat(prepare string, args ...interface{}) (sum float64, err er
 			}
 		}
 		return
-	}).Prepare(prepare).Args(args...).Query()
+	}).Prepare(prepare).Args(args...).QueryContext(ctx)
 	return
 }
 
 // Exists sql data exists
-func (s *Hat) Exists(prepare string, args ...interface{}) (exists bool, err error) {
+func (s *Hat) Exists(prepare string, args ...interface{}) (bool, error) {
+	return s.ExistsContext(context.Background(), prepare, args...)
+}
+
+// ExistsContext sql data exists with context
+func (s *Hat) ExistsContext(ctx context.Context, prepare string, args ...interface{}) (exists bool, err error) {
 	err = s.Scan(func(rows *sql.Rows) (err error) {
 		if rows.Next() {
 			exists = true
 		}
 		return
-	}).Prepare(prepare).Args(args...).Query()
+	}).Prepare(prepare).Args(args...).QueryContext(ctx)
 	return
 }
 
 // JsonFirst scan first one to fetch, fetch should be *AnyStruct
-func (s *Hat) JsonFirst(fetch interface{}) (empty bool, err error) {
-	var rows *sql.Rows
-	rows, err = s.stmtQuery()
-	if err != nil {
-		return
-	}
-	defer rows.Close()
+func (s *Hat) JsonFirst(fetch interface{}) (bool, error) {
+	return s.JsonFirstContext(context.Background(), fetch)
+}
+
+// JsonFirstContext scan first one to fetch with context, fetch should be *AnyStruct
+func (s *Hat) JsonFirstContext(ctx context.Context, fetch interface{}) (empty bool, err error) {
 	var first map[string]interface{}
-	first, err = s.getFirst(rows)
+	err = s.query(ctx, func(rows *sql.Rows) (err error) {
+		first, err = s.getFirst(rows)
+		return
+	})
 	if err != nil {
 		return
 	}
@@ -407,15 +781,17 @@ func (s *Hat) JsonFirst(fetch interface{}) (empty bool, err error) {
 }
 
 // JsonAll scan all to fetch, fetch should be one of *[]AnyStruct, *[]*AnyStruct
-func (s *Hat) JsonAll(fetch interface{}) (err error) {
-	var rows *sql.Rows
-	rows, err = s.stmtQuery()
-	if err != nil {
-		return
-	}
-	defer rows.Close()
+func (s *Hat) JsonAll(fetch interface{}) error {
+	return s.JsonAllContext(context.Background(), fetch)
+}
+
+// JsonAllContext scan all to fetch with context, fetch should be one of *[]AnyStruct, *[]*AnyStruct
+func (s *Hat) JsonAllContext(ctx context.Context, fetch interface{}) (err error) {
 	var all []map[string]interface{}
-	all, err = s.getAll(rows)
+	err = s.query(ctx, func(rows *sql.Rows) (err error) {
+		all, err = s.getAll(rows)
+		return
+	})
 	if err != nil {
 		return
 	}
@@ -424,26 +800,30 @@ func (s *Hat) JsonAll(fetch interface{}) (err error) {
 }
 
 // GetFirst scan first one to map[string]interface{} the query result is empty and return => nil, nil
-func (s *Hat) GetFirst() (first map[string]interface{}, err error) {
-	var rows *sql.Rows
-	rows, err = s.stmtQuery()
-	if err != nil {
+func (s *Hat) GetFirst() (map[string]interface{}, error) {
+	return s.GetFirstContext(context.Background())
+}
+
+// GetFirstContext scan first one to map[string]interface{} with context, the query result is empty and return => nil, nil
+func (s *Hat) GetFirstContext(ctx context.Context) (first map[string]interface{}, err error) {
+	err = s.query(ctx, func(rows *sql.Rows) (err error) {
+		first, err = s.getFirst(rows)
 		return
-	}
-	defer rows.Close()
-	first, err = s.getFirst(rows)
+	})
 	return
 }
 
 // GetAll scan all to []map[string]interface{}, the query result is empty and return => []map[string]interface{}{}, nil
-func (s *Hat) GetAll() (all []map[string]interface{}, err error) {
-	var rows *sql.Rows
-	rows, err = s.stmtQuery()
-	if err != nil {
+func (s *Hat) GetAll() ([]map[string]interface{}, error) {
+	return s.GetAllContext(context.Background())
+}
+
+// GetAllContext scan all to []map[string]interface{} with context, the query result is empty and return => []map[string]interface{}{}, nil
+func (s *Hat) GetAllContext(ctx context.Context) (all []map[string]interface{}, err error) {
+	err = s.query(ctx, func(rows *sql.Rows) (err error) {
+		all, err = s.getAll(rows)
 		return
-	}
-	defer rows.Close()
-	all, err = s.getAll(rows)
+	})
 	return
 }
 
@@ -506,6 +886,7 @@ func (s *Hat) getFirst(rows *sql.Rows) (first map[string]interface{}, err error)
 			return
 		}
 	}
+	observeRows(s.name, 1)
 	return
 }
 
@@ -538,30 +919,35 @@ func (s *Hat) getAll(rows *sql.Rows) (all []map[string]interface{}, err error) {
 		}
 		all = append(all, line)
 	}
+	observeRows(s.name, len(all))
 	return
 }
 
 // GetFirstByte scan first one to map[string][]byte, the query result is empty and return => nil, nil
-func (s *Hat) GetFirstByte() (first map[string][]byte, err error) {
-	var rows *sql.Rows
-	rows, err = s.stmtQuery()
-	if err != nil {
+func (s *Hat) GetFirstByte() (map[string][]byte, error) {
+	return s.GetFirstByteContext(context.Background())
+}
+
+// GetFirstByteContext scan first one to map[string][]byte with context, the query result is empty and return => nil, nil
+func (s *Hat) GetFirstByteContext(ctx context.Context) (first map[string][]byte, err error) {
+	err = s.query(ctx, func(rows *sql.Rows) (err error) {
+		first, err = s.getFirstByte(rows)
 		return
-	}
-	defer rows.Close()
-	first, err = s.getFirstByte(rows)
+	})
 	return
 }
 
 // GetAllByte scan all to []map[string][]byte, the query result is empty and return => []map[string][]byte{}, nil
-func (s *Hat) GetAllByte() (all []map[string][]byte, err error) {
-	var rows *sql.Rows
-	rows, err = s.stmtQuery()
-	if err != nil {
+func (s *Hat) GetAllByte() ([]map[string][]byte, error) {
+	return s.GetAllByteContext(context.Background())
+}
+
+// GetAllByteContext scan all to []map[string][]byte with context, the query result is empty and return => []map[string][]byte{}, nil
+func (s *Hat) GetAllByteContext(ctx context.Context) (all []map[string][]byte, err error) {
+	err = s.query(ctx, func(rows *sql.Rows) (err error) {
+		all, err = s.getAllByte(rows)
 		return
-	}
-	defer rows.Close()
-	all, err = s.getAllByte(rows)
+	})
 	return
 }
 
@@ -591,6 +977,7 @@ func (s *Hat) getFirstByte(rows *sql.Rows) (first map[string][]byte, err error)
 	for key, val := range tmp {
 		first[columns[key]] = val
 	}
+	observeRows(s.name, 1)
 	return
 }
 
@@ -623,5 +1010,6 @@ func (s *Hat) getAllByte(rows *sql.Rows) (all []map[string][]byte, err error) {
 		}
 		all = append(all, line)
 	}
+	observeRows(s.name, len(all))
 	return
 }
@@ -0,0 +1,33 @@
+package gomysql
+
+import (
+	"testing"
+)
+
+func TestIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"user", "`user`"},
+		{"db.user", "`db`.`user`"},
+		{"`db`.`user`", "`db`.`user`"},
+		{"u.id", "`u`.`id`"},
+		{"u.*", "`u`.*"},
+		{"user AS u", "`user` AS `u`"},
+		{"db.user as `u`", "`db`.`user` AS `u`"},
+		{"u.id AS user_id", "`u`.`id` AS `user_id`"},
+		{"COUNT(*) AS total", "COUNT(*) AS `total`"},
+		{"COUNT(*)", "COUNT(*)"},
+		{"my column", "`my column`"},
+		{"`my column`", "`my column`"},
+		{"`my AS column`", "`my AS column`"},
+		{"user u", "`user u`"},
+		{"has", "`has`"},
+	}
+	for _, test := range tests {
+		if got := Identifier(test.name); got != test.want {
+			t.Errorf("Identifier(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// Options database connection pool options, a zero value keeps the default of database/sql
+type Options struct {
+	MaxOpenConns    int           // max number of open connections, <= 0 unlimited
+	MaxIdleConns    int           // max number of idle connections, <= 0 use the default of database/sql
+	ConnMaxLifetime time.Duration // max time a connection may be reused, <= 0 forever
+	ConnMaxIdleTime time.Duration // max time a connection may be idle, <= 0 forever
+	Ping            bool          // ping the database after open, return the error if it can not be reached
+	PingRetries     int           // number of retries after the first ping failed
+	PingBackoff     time.Duration // wait time before the first retry, doubled for each retry, default 1 second
+	ConnectTimeout  time.Duration // timeout of each ping, also used as dial timeout by OpenConfig, <= 0 no timeout
+}
+
+// DefaultOptions options used by Open, OpenName and OpenCluster
+func DefaultOptions() *Options {
+	return &Options{
+		MaxOpenConns:    512,
+		MaxIdleConns:    128,
+		ConnMaxLifetime: time.Minute * 3,
+	}
+}
+
+// configure set the connection pool of database connect object
+func (s *Options) configure(database *sql.DB) {
+	if s.MaxOpenConns > 0 {
+		database.SetMaxOpenConns(s.MaxOpenConns)
+	}
+	if s.MaxIdleConns > 0 {
+		database.SetMaxIdleConns(s.MaxIdleConns)
+	}
+	if s.ConnMaxLifetime > 0 {
+		database.SetConnMaxLifetime(s.ConnMaxLifetime)
+	}
+	if s.ConnMaxIdleTime > 0 {
+		database.SetConnMaxIdleTime(s.ConnMaxIdleTime)
+	}
+}
+
+// ping ping the database until it is reached or the retries are used up
+func (s *Options) ping(database *sql.DB) (err error) {
+	backoff := s.PingBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for i := 0; ; i++ {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if s.ConnectTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, s.ConnectTimeout)
+		}
+		err = database.PingContext(ctx)
+		cancel()
+		if err == nil || i >= s.PingRetries {
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// NewDb open database connect object with options, nil options use DefaultOptions
+// dn: driver name, dsn: data source name
+func NewDb(dn string, dsn string, options *Options) (*sql.DB, error) {
+	if options == nil {
+		options = DefaultOptions()
+	}
+	database, err := sql.Open(dn, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return options.open(database)
+}
+
+// NewDbConnector open database connect object using connector with options, nil options use DefaultOptions
+func NewDbConnector(connector driver.Connector, options *Options) (*sql.DB, error) {
+	if options == nil {
+		options = DefaultOptions()
+	}
+	return options.open(sql.OpenDB(connector))
+}
+
+// open configure the connection pool and ping the database if required, the database is closed if it can not be reached
+func (s *Options) open(database *sql.DB) (*sql.DB, error) {
+	s.configure(database)
+	if s.Ping {
+		if err := s.ping(database); err != nil {
+			_ = database.Close()
+			return nil, err
+		}
+	}
+	return database, nil
+}
+
+// OpenOptions connect to mysql service with options and register the connection under name
+// dn: driver name, dsn: data source name
+func OpenOptions(name string, dn string, dsn string, options *Options) error {
+	database, err := NewDb(dn, dsn, options)
+	if err != nil {
+		return err
+	}
+	SetName(name, database)
+	return nil
+}
@@ -0,0 +1,104 @@
+package gomysql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultName the name of the default database connection, used by Open, Db0, Db1, Db2 and the package level helpers
+const DefaultName = ""
+
+// DefaultLabel the value of the label connection of the default database connection in metrics, prometheus drops labels with empty values
+// the metrics of a connection registered under this name can not be told apart from the default connection, so do not use it as a name
+const DefaultLabel = "(default)"
+
+// registry named database connection objects
+var registry = struct {
+	sync.RWMutex
+	dbs      map[string]*sql.DB
+	clusters map[string]*Cluster
+}{
+	dbs:      map[string]*sql.DB{},
+	clusters: map[string]*Cluster{},
+}
+
+// OpenName connect to mysql service and register the connection under name
+// dn: driver name, dsn: data source name
+func OpenName(name string, dn string, dsn string) error {
+	return OpenOptions(name, dn, dsn, DefaultOptions())
+}
+
+// SetName register database connect object under name, an existing connection with the same name is replaced but not closed
+func SetName(name string, database *sql.DB) {
+	registry.Lock()
+	defer registry.Unlock()
+	old := registry.dbs[name]
+	defer forget(old)
+	delete(registry.clusters, name)
+	if database == nil {
+		delete(registry.dbs, name)
+		return
+	}
+	registry.dbs[name] = database
+}
+
+// SetCluster register cluster under name, the primary of the cluster is registered as the database connect object of name
+func SetCluster(name string, cluster *Cluster) {
+	registry.Lock()
+	defer registry.Unlock()
+	old := registry.dbs[name]
+	defer forget(old)
+	if cluster == nil {
+		delete(registry.clusters, name)
+		delete(registry.dbs, name)
+		return
+	}
+	registry.clusters[name] = cluster
+	registry.dbs[name] = cluster.Primary()
+}
+
+// GetCluster get cluster registered under name, return nil if it does not exist
+func GetCluster(name string) *Cluster {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.clusters[name]
+}
+
+// GetName get database connect object registered under name, return nil if it does not exist
+func GetName(name string) *sql.DB {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.dbs[name]
+}
+
+// CloseName close the database connect object registered under name and remove it from the registry
+func CloseName(name string) error {
+	registry.Lock()
+	database, ok := registry.dbs[name]
+	cluster := registry.clusters[name]
+	delete(registry.dbs, name)
+	delete(registry.clusters, name)
+	forget(database)
+	registry.Unlock()
+	if !ok {
+		return fmt.Errorf("database connection %q is not registered", name)
+	}
+	if cluster != nil {
+		return cluster.Close()
+	}
+	return database.Close()
+}
+
+// Names get the names of all registered database connections in ascending order
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.dbs))
+	for name := range registry.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,290 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ReplicaState health state of replica
+type ReplicaState int32
+
+const (
+	ReplicaHealthy   ReplicaState = iota // the replica is in rotation
+	ReplicaUnhealthy                     // the replica can not be reached, ejected from rotation
+	ReplicaLagging                       // the replication lag of the replica is too large or unknown, ejected from rotation
+)
+
+// String state name
+func (s ReplicaState) String() string {
+	switch s {
+	case ReplicaHealthy:
+		return "healthy"
+	case ReplicaUnhealthy:
+		return "unhealthy"
+	case ReplicaLagging:
+		return "lagging"
+	}
+	return "unknown(" + strconv.Itoa(int(s)) + ")"
+}
+
+// ReplicaHealth the result of checking a replica once
+type ReplicaHealth struct {
+	Replica   *Replica      // checked replica
+	State     ReplicaState  // state observed by this check, not necessarily the state of the replica
+	Lag       time.Duration // replication lag, -1 if it is unknown or not measured
+	Err       error         // error of ping or lag query
+	CheckedAt time.Time     // check time
+}
+
+// replicaCounter consecutive check results of a replica
+type replicaCounter struct {
+	failures  int
+	successes int
+}
+
+// ReplicaSet check the health of the replicas of cluster in background
+// unreachable or lagging replicas are ejected from rotation and brought back when recovered
+// reads fall back to the primary when none of the replicas is healthy
+type ReplicaSet struct {
+	Interval   time.Duration // check interval, default 5 seconds
+	Timeout    time.Duration // timeout of checking one replica, default 2 seconds
+	MaxLag     time.Duration // max replication lag, <= 0 skip measuring the lag
+	Failures   int           // consecutive failed checks before a replica is ejected, default 1
+	Recoveries int           // consecutive successful checks before an ejected replica is brought back, default 1
+
+	OnCheck    func(health *ReplicaHealth)                                                       // called after every check of a replica
+	OnChange   func(replica *Replica, from ReplicaState, to ReplicaState, health *ReplicaHealth) // called when the state of a replica changed
+	OnFallback func(fallback bool)                                                               // called with true when reads fall back to the primary, with false when a replica is available again
+
+	cluster  *Cluster
+	mutex    sync.Mutex
+	counters map[*Replica]*replicaCounter
+	fallback bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewReplicaSet create replica set of the cluster
+func NewReplicaSet(cluster *Cluster) *ReplicaSet {
+	return &ReplicaSet{
+		Interval:   time.Second * 5,
+		Timeout:    time.Second * 2,
+		Failures:   1,
+		Recoveries: 1,
+		cluster:    cluster,
+		counters:   map[*Replica]*replicaCounter{},
+	}
+}
+
+// Cluster get the cluster of the replica set
+func (s *ReplicaSet) Cluster() *Cluster {
+	return s.cluster
+}
+
+// Start check the replicas immediately, then check them every interval in background until Stop is called
+func (s *ReplicaSet) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+}
+
+// Stop stop checking in background and wait for the running check to finish, the replicas keep their current state
+func (s *ReplicaSet) Stop() {
+	s.mutex.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mutex.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// run check loop
+func (s *ReplicaSet) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second * 5
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check check all replicas once and update their state
+func (s *ReplicaSet) Check(ctx context.Context) {
+	for _, replica := range s.cluster.replicas {
+		if ctx.Err() != nil {
+			return
+		}
+		health := s.check(ctx, replica)
+		if s.OnCheck != nil {
+			s.OnCheck(health)
+		}
+		s.update(health)
+	}
+	fallback := len(s.cluster.replicas) > 0 && len(s.cluster.Available()) == 0
+	s.mutex.Lock()
+	changed := fallback != s.fallback
+	s.fallback = fallback
+	s.mutex.Unlock()
+	if changed && s.OnFallback != nil {
+		s.OnFallback(fallback)
+	}
+}
+
+// check ping the replica and measure its replication lag
+func (s *ReplicaSet) check(ctx context.Context, replica *Replica) (health *ReplicaHealth) {
+	health = &ReplicaHealth{
+		Replica:   replica,
+		State:     ReplicaHealthy,
+		Lag:       -1,
+		CheckedAt: time.Now(),
+	}
+	if replica.Db == nil {
+		health.State = ReplicaUnhealthy
+		return
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	health.Err = replica.Db.PingContext(ctx)
+	if health.Err != nil {
+		health.State = ReplicaUnhealthy
+		return
+	}
+	if s.MaxLag <= 0 {
+		return
+	}
+	health.Lag, health.Err = ReplicationLag(ctx, replica.Db)
+	if health.Err != nil {
+		health.State = ReplicaUnhealthy
+		return
+	}
+	if health.Lag < 0 || health.Lag > s.MaxLag {
+		health.State = ReplicaLagging
+	}
+	return
+}
+
+// update apply the check result to the replica state
+func (s *ReplicaSet) update(health *ReplicaHealth) {
+	replica := health.Replica
+	s.mutex.Lock()
+	counter, ok := s.counters[replica]
+	if !ok {
+		counter = &replicaCounter{}
+		s.counters[replica] = counter
+	}
+	current := replica.State()
+	next := current
+	if health.State == ReplicaHealthy {
+		counter.failures = 0
+		counter.successes++
+		if current != ReplicaHealthy && counter.successes >= s.Recoveries {
+			next = ReplicaHealthy
+		}
+	} else {
+		counter.successes = 0
+		counter.failures++
+		if current == ReplicaHealthy && counter.failures >= s.Failures {
+			next = health.State
+		}
+		// an ejected replica may change from unhealthy to lagging and vice versa
+		if current != ReplicaHealthy {
+			next = health.State
+		}
+	}
+	s.mutex.Unlock()
+	if next == current {
+		return
+	}
+	replica.setState(next)
+	if s.OnChange != nil {
+		s.OnChange(replica, current, next, health)
+	}
+}
+
+// ReplicationLag query the replication lag of replica, -1 means the replication is not running
+// SHOW REPLICA STATUS is used first, SHOW SLAVE STATUS for servers older than MySQL 8.0.22
+func ReplicationLag(ctx context.Context, replica *sql.DB) (time.Duration, error) {
+	lag, err := replicationLag(ctx, replica, "SHOW REPLICA STATUS", "Seconds_Behind_Source")
+	if err == nil {
+		return lag, nil
+	}
+	// 1064: ER_PARSE_ERROR
+	if me, ok := err.(*mysql.MySQLError); ok && me.Number == 1064 {
+		return replicationLag(ctx, replica, "SHOW SLAVE STATUS", "Seconds_Behind_Master")
+	}
+	return lag, err
+}
+
+// replicationLag query the replication lag using query, read the seconds from column
+func replicationLag(ctx context.Context, replica *sql.DB, query string, column string) (lag time.Duration, err error) {
+	lag = -1
+	var rows *sql.Rows
+	rows, err = replica.QueryContext(ctx, query)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	var columns []string
+	columns, err = rows.Columns()
+	if err != nil {
+		return
+	}
+	if !rows.Next() {
+		// not a replica
+		err = rows.Err()
+		return
+	}
+	tmp := make([]sql.RawBytes, len(columns))
+	scanner := make([]interface{}, len(columns))
+	for i := range tmp {
+		scanner[i] = &tmp[i]
+	}
+	err = rows.Scan(scanner...)
+	if err != nil {
+		return
+	}
+	for key, val := range columns {
+		if !strings.EqualFold(val, column) {
+			continue
+		}
+		// NULL: the replication is not running
+		if tmp[key] == nil {
+			return
+		}
+		var seconds int64
+		seconds, err = strconv.ParseInt(string(tmp[key]), 10, 64)
+		if err != nil {
+			return
+		}
+		lag = time.Duration(seconds) * time.Second
+		return
+	}
+	return
+}
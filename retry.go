@@ -0,0 +1,103 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Retry retry policy of transaction
+type Retry struct {
+	Attempts   int                  // max attempts including the first one, default 3
+	Backoff    time.Duration        // wait time before the first retry, doubled for each retry, default 50 milliseconds
+	MaxBackoff time.Duration        // max wait time before a retry, default 1 second
+	Retryable  func(err error) bool // classify the error of an attempt, default IsRetryable
+	TxOptions  *sql.TxOptions       // options of every transaction, such as isolation level
+}
+
+// DefaultRetry default retry policy
+func DefaultRetry() *Retry {
+	return &Retry{
+		Attempts:   3,
+		Backoff:    time.Millisecond * 50,
+		MaxBackoff: time.Second,
+		Retryable:  IsRetryable,
+	}
+}
+
+// wait the wait time before the retry after attempt failed, full jitter of the exponential backoff
+func (s *Retry) wait(attempt int) time.Duration {
+	backoff, max := s.Backoff, s.MaxBackoff
+	if backoff <= 0 {
+		backoff = time.Millisecond * 50
+	}
+	if max <= 0 {
+		max = time.Second
+	}
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// IsRetryable the error is a deadlock (1213: ER_LOCK_DEADLOCK) or a lock wait timeout (1205: ER_LOCK_WAIT_TIMEOUT)
+// the transaction that returned such an error can succeed if it is executed again
+func IsRetryable(err error) bool {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	return me.Number == 1213 || me.Number == 1205
+}
+
+// TransactionRetry closure execute transaction, automatic rollback on error, re-execute the closure with a new transaction if the error is retryable
+// nil retry uses DefaultRetry, a transaction nested in an opened transaction is not retried because the error rolls back the outer transaction
+func (s *Hat) TransactionRetry(ctx context.Context, retry *Retry, closure func(hat *Hat) (err error)) (err error) {
+	if retry == nil {
+		retry = DefaultRetry()
+	}
+	attempts := retry.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	retryable := retry.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	nested := s.session.tx != nil
+	for attempt := 1; ; attempt++ {
+		err = s.TransactionTx(ctx, retry.TxOptions, closure)
+		if err == nil || nested || attempt >= attempts || !retryable(err) {
+			return
+		}
+		timer := time.NewTimer(retry.wait(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// TransactionRetry closures execute transaction, err != nil auto rollback, re-execute the closure with a new transaction if the error is retryable
+func (s *Curd) TransactionRetry(ctx context.Context, retry *Retry, closure func(curd *Curd) (err error)) error {
+	return s.hat.TransactionRetry(ctx, retry, func(hat *Hat) error { return closure(s.clone(hat)) })
+}
+
+// TransactionRetry transaction execution, automatic rollback on error, re-execute the closure with a new transaction if the error is retryable
+func (s *Client) TransactionRetry(ctx context.Context, retry *Retry, closure func(hat *Hat) (err error)) error {
+	return s.Hat().TransactionRetry(ctx, retry, closure)
+}
+
+// TransactionRetry transaction execution, automatic rollback on error, re-execute the closure with a new transaction if the error is retryable
+func TransactionRetry(ctx context.Context, retry *Retry, closure func(hat *Hat) (err error)) error {
+	return Use(DefaultName).TransactionRetry(ctx, retry, closure)
+}
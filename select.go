@@ -0,0 +1,271 @@
+package gomysql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Select SELECT statement builder, PrepareArgs renders the prepared sql statement and its parameters
+type Select struct {
+	table      string        // table name, with alias such as user AS u
+	distinct   bool          // SELECT DISTINCT
+	columns    []string      // selected columns, all columns if it is empty
+	joins      []*joined     // joined tables in order
+	where      []string      // conditions of WHERE, joined by AND
+	whereArgs  []interface{} // parameters of conditions of WHERE
+	group      []string      // columns of GROUP BY
+	having     []string      // conditions of HAVING, joined by AND
+	havingArgs []interface{} // parameters of conditions of HAVING
+	order      []string      // columns of ORDER BY with direction
+	limit      int64         // LIMIT, 0 means no limit
+	offset     int64         // OFFSET
+	err        error         // the first error of building, returned when the statement is executed
+}
+
+// joined table joined by the SELECT statement
+type joined struct {
+	kind  string        // INNER JOIN, LEFT JOIN, RIGHT JOIN, CROSS JOIN
+	table string        // table name, with alias such as order AS o
+	on    string        // condition of ON
+	args  []interface{} // parameters of condition of ON
+}
+
+// NewSelect new SELECT statement builder of table, table may have an alias such as user AS u
+func NewSelect(table string) *Select {
+	return &Select{
+		table: table,
+	}
+}
+
+// Table set table name
+func (s *Select) Table(table string) *Select {
+	s.table = table
+	return s
+}
+
+// Distinct select distinct rows
+func (s *Select) Distinct() *Select {
+	s.distinct = true
+	return s
+}
+
+// Column append selected columns, columns containing function calls such as COUNT(*) are not quoted
+func (s *Select) Column(columns ...string) *Select {
+	s.columns = append(s.columns, columns...)
+	return s
+}
+
+// Where append condition of WHERE, where is a sql condition string with args, a Condition, or a map[string]interface{}, the conditions are joined by AND
+func (s *Select) Where(where interface{}, args ...interface{}) *Select {
+	cond, args, err := whereArgs(where, args)
+	if err != nil {
+		s.fail(err)
+		return s
+	}
+	if cond == "" {
+		return s
+	}
+	s.where = append(s.where, cond)
+	s.whereArgs = append(s.whereArgs, args...)
+	return s
+}
+
+// join append the joined table, the condition of ON is required except CROSS JOIN
+func (s *Select) join(kind string, table string, on interface{}, args []interface{}) *Select {
+	cond, args, err := whereArgs(on, args)
+	if err != nil {
+		s.fail(err)
+		return s
+	}
+	if cond == "" && kind != "CROSS JOIN" {
+		s.fail(fmt.Errorf("please set the condition of %s %s", kind, table))
+		return s
+	}
+	s.joins = append(s.joins, &joined{
+		kind:  kind,
+		table: table,
+		on:    cond,
+		args:  args,
+	})
+	return s
+}
+
+// Join INNER JOIN table ON on, on is a sql condition string with args, a Condition such as On("u.id", "o.user_id"), or a map[string]interface{}
+func (s *Select) Join(table string, on interface{}, args ...interface{}) *Select {
+	return s.join("INNER JOIN", table, on, args)
+}
+
+// LeftJoin LEFT JOIN table ON on
+func (s *Select) LeftJoin(table string, on interface{}, args ...interface{}) *Select {
+	return s.join("LEFT JOIN", table, on, args)
+}
+
+// RightJoin RIGHT JOIN table ON on
+func (s *Select) RightJoin(table string, on interface{}, args ...interface{}) *Select {
+	return s.join("RIGHT JOIN", table, on, args)
+}
+
+// CrossJoin CROSS JOIN table
+func (s *Select) CrossJoin(table string) *Select {
+	return s.join("CROSS JOIN", table, nil, nil)
+}
+
+// Group append columns of GROUP BY
+func (s *Select) Group(columns ...string) *Select {
+	s.group = append(s.group, columns...)
+	return s
+}
+
+// Having append condition of HAVING, having is a sql condition string with args, a Condition, or a map[string]interface{}, the conditions are joined by AND
+func (s *Select) Having(having interface{}, args ...interface{}) *Select {
+	cond, args, err := whereArgs(having, args)
+	if err != nil {
+		s.fail(err)
+		return s
+	}
+	if cond == "" {
+		return s
+	}
+	s.having = append(s.having, cond)
+	s.havingArgs = append(s.havingArgs, args...)
+	return s
+}
+
+// fail record the first error of building
+func (s *Select) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Err get the first error of building, such as an unsupported type of where
+func (s *Select) Err() error {
+	return s.err
+}
+
+// Asc append columns of ORDER BY in ascending order
+func (s *Select) Asc(columns ...string) *Select {
+	for _, column := range columns {
+		s.order = append(s.order, fmt.Sprintf("%s ASC", Identifier(column)))
+	}
+	return s
+}
+
+// Desc append columns of ORDER BY in descending order
+func (s *Select) Desc(columns ...string) *Select {
+	for _, column := range columns {
+		s.order = append(s.order, fmt.Sprintf("%s DESC", Identifier(column)))
+	}
+	return s
+}
+
+// Limit set the maximum number of rows, 0 means no limit
+func (s *Select) Limit(limit int64) *Select {
+	s.limit = limit
+	return s
+}
+
+// Offset set the number of rows skipped, it works with Limit only
+func (s *Select) Offset(offset int64) *Select {
+	s.offset = offset
+	return s
+}
+
+// Page set Limit and Offset of the page, page starts from 1
+func (s *Select) Page(page int64, size int64) *Select {
+	if page < 1 {
+		page = 1
+	}
+	s.limit = size
+	s.offset = (page - 1) * size
+	return s
+}
+
+// identifiers quote columns by quote and join them by comma
+func identifiers(columns []string, quote func(s string) string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quote(column))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// reference quote the table or the selected column of the builder, the alias may follow it without AS
+// such as db.user AS u => `db`.`user` AS `u`, order o => `order` AS `o`, COUNT(*) AS total => COUNT(*) AS `total`, u.* => `u`.*
+func reference(s string) string {
+	if expression, alias, ok := identifierAlias(s, true); ok {
+		return fmt.Sprintf("%s AS %s", reference(expression), Identifier(alias))
+	}
+	if s = strings.TrimSpace(s); s == "*" {
+		return s
+	}
+	return Identifier(s)
+}
+
+// conditions join conditions by AND, every condition is enclosed in parentheses
+func conditions(where []string) string {
+	return fmt.Sprintf("( %s )", strings.Join(where, " ) AND ( "))
+}
+
+// PrepareArgs get prepared sql statement and parameter list of prepared sql statement
+func (s *Select) PrepareArgs() (prepare string, args []interface{}) {
+	var builder strings.Builder
+	builder.WriteString("SELECT ")
+	if s.distinct {
+		builder.WriteString("DISTINCT ")
+	}
+	if len(s.columns) == 0 {
+		builder.WriteString("*")
+	} else {
+		builder.WriteString(identifiers(s.columns, reference))
+	}
+	builder.WriteString(" FROM ")
+	builder.WriteString(reference(s.table))
+	for _, tmp := range s.joins {
+		builder.WriteString(fmt.Sprintf(" %s %s", tmp.kind, reference(tmp.table)))
+		if tmp.on != "" {
+			builder.WriteString(fmt.Sprintf(" ON ( %s )", tmp.on))
+			args = append(args, tmp.args...)
+		}
+	}
+	if len(s.where) > 0 {
+		builder.WriteString(" WHERE ")
+		builder.WriteString(conditions(s.where))
+		args = append(args, s.whereArgs...)
+	}
+	if len(s.group) > 0 {
+		builder.WriteString(" GROUP BY ")
+		builder.WriteString(identifiers(s.group, Identifier))
+	}
+	if len(s.having) > 0 {
+		builder.WriteString(" HAVING ")
+		builder.WriteString(conditions(s.having))
+		args = append(args, s.havingArgs...)
+	}
+	if len(s.order) > 0 {
+		builder.WriteString(" ORDER BY ")
+		builder.WriteString(strings.Join(s.order, ", "))
+	}
+	if s.limit > 0 {
+		builder.WriteString(fmt.Sprintf(" LIMIT %d", s.limit))
+		if s.offset > 0 {
+			builder.WriteString(fmt.Sprintf(" OFFSET %d", s.offset))
+		}
+	}
+	builder.WriteString(";")
+	prepare = builder.String()
+	return
+}
+
+// Select get a copy of hat with the prepared sql statement and parameter list rendered by query, the error of building query is returned when it is executed
+func (s *Hat) Select(query *Select) *Hat {
+	prepare, args := query.PrepareArgs()
+	tmp := s.Prepare(prepare).Args(args...)
+	tmp.err = query.Err()
+	return tmp
+}
+
+// Select get a hat of curd with the prepared sql statement and parameter list rendered by query
+func (s *Curd) Select(query *Select) *Hat {
+	return s.hat.Select(query)
+}
@@ -0,0 +1,42 @@
+package gomysql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectJoin(t *testing.T) {
+	query := NewSelect("user u").
+		Column("u.*", "o.id order_id", "COUNT(*) AS total").
+		Join("order AS o", On("u.id", "o.user_id")).
+		LeftJoin("payment p", And(On("p.order_id", "o.id"), Eq("p.status", 1))).
+		CrossJoin("tag").
+		Where(Where{"u.status": 1}).
+		Group("u.id").
+		Desc("total")
+	prepare, args := query.PrepareArgs()
+	want := "SELECT `u`.*, `o`.`id` AS `order_id`, COUNT(*) AS `total` FROM `user` AS `u` INNER JOIN `order` AS `o` ON ( `u`.`id` = `o`.`user_id` )" +
+		" LEFT JOIN `payment` AS `p` ON ( ( `p`.`order_id` = `o`.`id` ) AND ( `p`.`status` = ? ) ) CROSS JOIN `tag`" +
+		" WHERE ( `u`.`status` = ? ) GROUP BY `u`.`id` ORDER BY `total` DESC;"
+	if prepare != want {
+		t.Errorf("prepare %q, want %q", prepare, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 1}) {
+		t.Errorf("args %#v", args)
+	}
+	if err := query.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSelectJoinWithoutCondition(t *testing.T) {
+	for _, query := range []*Select{
+		NewSelect("user u").Join("order o", ""),
+		NewSelect("user u").LeftJoin("order o", nil),
+		NewSelect("user u").RightJoin("order o", ""),
+	} {
+		if query.Err() == nil {
+			t.Error("join without condition is not refused")
+		}
+	}
+}
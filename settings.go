@@ -0,0 +1,388 @@
+package gomysql
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingsDefault the name of the default connection in settings file
+const SettingsDefault = "default"
+
+// Duration time.Duration that can be read from "3m", "1s" or a number of seconds
+type Duration time.Duration
+
+// UnmarshalJSON parse duration from json string or number
+func (s *Duration) UnmarshalJSON(bts []byte) error {
+	str := strings.Trim(string(bts), `"`)
+	tmp, err := parseDuration(str)
+	if err != nil {
+		return err
+	}
+	*s = Duration(tmp)
+	return nil
+}
+
+// MarshalJSON format duration as json string
+func (s Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(s).String())
+}
+
+// parseDuration parse "3m", "1s" or a number of seconds
+func parseDuration(str string) (time.Duration, error) {
+	if str == "" || str == "null" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseFloat(str, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(str)
+}
+
+// Connection settings of a named connection
+// the replicas inherit the empty fields of their config from the primary
+type Connection struct {
+	Config
+	MaxOpenConns    int       `json:"max_open_conns"`     // see Options
+	MaxIdleConns    int       `json:"max_idle_conns"`     // see Options
+	ConnMaxLifetime Duration  `json:"conn_max_lifetime"`  // see Options
+	ConnMaxIdleTime Duration  `json:"conn_max_idle_time"` // see Options
+	Ping            bool      `json:"ping"`               // see Options
+	PingRetries     int       `json:"ping_retries"`       // see Options
+	PingBackoff     Duration  `json:"ping_backoff"`       // see Options
+	ConnectTimeout  Duration  `json:"connect_timeout"`    // see Options
+	Replicas        []*Config `json:"replicas"`           // read only replicas, the connection is opened as a cluster if it is not empty
+
+	key func(field string) string // the key of field in the settings source, used by error messages
+}
+
+// Options get the connection pool options
+func (s *Connection) Options() *Options {
+	return &Options{
+		MaxOpenConns:    s.MaxOpenConns,
+		MaxIdleConns:    s.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(s.ConnMaxLifetime),
+		ConnMaxIdleTime: time.Duration(s.ConnMaxIdleTime),
+		Ping:            s.Ping,
+		PingRetries:     s.PingRetries,
+		PingBackoff:     time.Duration(s.PingBackoff),
+		ConnectTimeout:  time.Duration(s.ConnectTimeout),
+	}
+}
+
+// replica get the config of the replica, the empty fields are inherited from the primary
+func (s *Connection) replica(key int) *Config {
+	replica := *s.Replicas[key]
+	if replica.Host == "" && replica.Socket == "" {
+		return &replica
+	}
+	if replica.Port == 0 && replica.Socket == "" {
+		replica.Port = s.Port
+	}
+	if replica.User == "" {
+		replica.User, replica.Password = s.User, s.Password
+	}
+	if replica.Database == "" {
+		replica.Database = s.Database
+	}
+	if replica.Charset == "" {
+		replica.Charset = s.Charset
+	}
+	if replica.Collation == "" {
+		replica.Collation = s.Collation
+	}
+	if replica.Params == nil {
+		replica.Params = s.Params
+	}
+	return &replica
+}
+
+// Validate check the connection settings, the error names the invalid key
+func (s *Connection) Validate() error {
+	key := s.key
+	if key == nil {
+		key = func(field string) string { return field }
+	}
+	if s.User == "" {
+		return fmt.Errorf("config key %q is required", key("user"))
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("config key %q is invalid: port %d out of range", key("port"), s.Port)
+	}
+	negatives := []struct {
+		field string
+		value int64
+	}{
+		{"max_open_conns", int64(s.MaxOpenConns)},
+		{"max_idle_conns", int64(s.MaxIdleConns)},
+		{"ping_retries", int64(s.PingRetries)},
+		{"conn_max_lifetime", int64(s.ConnMaxLifetime)},
+		{"conn_max_idle_time", int64(s.ConnMaxIdleTime)},
+		{"ping_backoff", int64(s.PingBackoff)},
+		{"connect_timeout", int64(s.ConnectTimeout)},
+	}
+	for _, val := range negatives {
+		if val.value < 0 {
+			return fmt.Errorf("config key %q is invalid: the value is negative", key(val.field))
+		}
+	}
+	for i, replica := range s.Replicas {
+		field := fmt.Sprintf("replicas.%d.host", i)
+		if replica == nil || replica.Host == "" && replica.Socket == "" {
+			return fmt.Errorf("config key %q is required", key(field))
+		}
+		if replica.Port < 0 || replica.Port > 65535 {
+			return fmt.Errorf("config key %q is invalid: port %d out of range", key(fmt.Sprintf("replicas.%d.port", i)), replica.Port)
+		}
+	}
+	return nil
+}
+
+// dsn build the data source name of config, apply the connect timeout as dial timeout
+func (s *Connection) dsn(config *Config) (string, error) {
+	cfg, err := config.mysql()
+	if err != nil {
+		return "", err
+	}
+	if s.ConnectTimeout > 0 && cfg.Timeout == 0 {
+		cfg.Timeout = time.Duration(s.ConnectTimeout)
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// Open connect to mysql service and register the connection or cluster under name
+func (s *Connection) Open(name string) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	primary, err := s.dsn(&s.Config)
+	if err != nil {
+		return err
+	}
+	if len(s.Replicas) == 0 {
+		return OpenOptions(name, "mysql", primary, s.Options())
+	}
+	replicas := make([]string, len(s.Replicas))
+	for key := range s.Replicas {
+		replicas[key], err = s.dsn(s.replica(key))
+		if err != nil {
+			return err
+		}
+	}
+	return OpenClusterOptions(name, "mysql", s.Options(), primary, replicas...)
+}
+
+// Settings connection settings of several named connections
+type Settings struct {
+	Connections map[string]*Connection `json:"connections"` // the connection named SettingsDefault is registered as the default connection
+}
+
+// names connection names in ascending order
+func (s *Settings) names() []string {
+	names := make([]string, 0, len(s.Connections))
+	for name := range s.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate check all connection settings before connecting, the error names the invalid key
+func (s *Settings) Validate() error {
+	if len(s.Connections) == 0 {
+		return fmt.Errorf("config key %q is required", "connections")
+	}
+	for _, name := range s.names() {
+		connection := s.Connections[name]
+		if connection == nil {
+			return fmt.Errorf("config key %q is required", "connections."+name)
+		}
+		if err := connection.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open validate all connection settings, then connect to mysql service and register the connections
+// the connections opened by this call are closed if any of them fails
+func (s *Settings) Open() error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	var opened []string
+	for _, name := range s.names() {
+		register := name
+		if register == SettingsDefault {
+			register = DefaultName
+		}
+		if err := s.Connections[name].Open(register); err != nil {
+			for _, tmp := range opened {
+				_ = CloseName(tmp)
+			}
+			return fmt.Errorf("open connection %q: %w", name, err)
+		}
+		opened = append(opened, register)
+	}
+	return nil
+}
+
+// LoadFile load settings from json file
+//
+//	{
+//	    "connections": {
+//	        "default": {"host": "127.0.0.1", "port": 3306, "user": "root", "password": "", "database": "test", "charset": "utf8mb4", "max_open_conns": 16, "conn_max_lifetime": "3m"},
+//	        "orders": {"host": "10.0.0.1", "user": "orders", "database": "orders", "replicas": [{"host": "10.0.0.2"}, {"host": "10.0.0.3"}]}
+//	    }
+//	}
+func LoadFile(path string) (*Settings, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := &Settings{}
+	if err = json.Unmarshal(bts, settings); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	for name, connection := range settings.Connections {
+		if connection == nil {
+			continue
+		}
+		prefix := "connections." + name + "."
+		connection.key = func(field string) string { return prefix + field }
+	}
+	return settings, nil
+}
+
+// LoadEnv load settings from environment variables with prefix, such as prefix MYSQL
+// the default connection: MYSQL_HOST, MYSQL_PORT, MYSQL_USER, MYSQL_PASSWORD, MYSQL_DATABASE, MYSQL_CHARSET, MYSQL_COLLATION, MYSQL_SOCKET,
+// MYSQL_PARAMS (parseTime=true&loc=Local), MYSQL_MAX_OPEN_CONNS, MYSQL_MAX_IDLE_CONNS, MYSQL_CONN_MAX_LIFETIME, MYSQL_CONN_MAX_IDLE_TIME,
+// MYSQL_PING, MYSQL_PING_RETRIES, MYSQL_PING_BACKOFF, MYSQL_CONNECT_TIMEOUT, MYSQL_REPLICAS (host1:3306,host2:3306)
+// other connections are listed in MYSQL_CONNECTIONS (orders,users), their keys are MYSQL_ORDERS_HOST, MYSQL_USERS_HOST ...
+func LoadEnv(prefix string) (*Settings, error) {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+	settings := &Settings{
+		Connections: map[string]*Connection{},
+	}
+	names := []string{SettingsDefault}
+	if list := os.Getenv(prefix + "_CONNECTIONS"); list != "" {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		infix := prefix + "_"
+		if name != SettingsDefault {
+			infix += strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		}
+		connection, found, err := loadEnvConnection(infix)
+		if err != nil {
+			return nil, err
+		}
+		// the default connection is optional when other connections are listed, otherwise Validate names its missing keys such as MYSQL_USER
+		if !found && name == SettingsDefault && len(names) > 1 {
+			continue
+		}
+		settings.Connections[name] = connection
+	}
+	return settings, nil
+}
+
+// loadEnvConnection load the settings of a connection from environment variables starting with infix
+func loadEnvConnection(infix string) (connection *Connection, found bool, err error) {
+	connection = &Connection{
+		key: func(field string) string {
+			return infix + strings.ToUpper(field)
+		},
+	}
+	lookup := func(field string) (string, bool) {
+		val, ok := os.LookupEnv(infix + strings.ToUpper(field))
+		found = found || ok
+		return val, ok && val != ""
+	}
+	strs := map[string]*string{
+		"host":      &connection.Host,
+		"socket":    &connection.Socket,
+		"user":      &connection.User,
+		"password":  &connection.Password,
+		"database":  &connection.Database,
+		"charset":   &connection.Charset,
+		"collation": &connection.Collation,
+	}
+	for field, ptr := range strs {
+		if val, ok := lookup(field); ok {
+			*ptr = val
+		}
+	}
+	ints := map[string]*int{
+		"port":           &connection.Port,
+		"max_open_conns": &connection.MaxOpenConns,
+		"max_idle_conns": &connection.MaxIdleConns,
+		"ping_retries":   &connection.PingRetries,
+	}
+	for field, ptr := range ints {
+		if val, ok := lookup(field); ok {
+			if *ptr, err = strconv.Atoi(val); err != nil {
+				err = fmt.Errorf("config key %q is invalid: %w", connection.key(field), err)
+				return
+			}
+		}
+	}
+	durations := map[string]*Duration{
+		"conn_max_lifetime":  &connection.ConnMaxLifetime,
+		"conn_max_idle_time": &connection.ConnMaxIdleTime,
+		"ping_backoff":       &connection.PingBackoff,
+		"connect_timeout":    &connection.ConnectTimeout,
+	}
+	for field, ptr := range durations {
+		if val, ok := lookup(field); ok {
+			var tmp time.Duration
+			if tmp, err = parseDuration(val); err != nil {
+				err = fmt.Errorf("config key %q is invalid: %w", connection.key(field), err)
+				return
+			}
+			*ptr = Duration(tmp)
+		}
+	}
+	if val, ok := lookup("ping"); ok {
+		if connection.Ping, err = strconv.ParseBool(val); err != nil {
+			err = fmt.Errorf("config key %q is invalid: %w", connection.key("ping"), err)
+			return
+		}
+	}
+	if val, ok := lookup("params"); ok {
+		connection.Params = map[string]string{}
+		for _, pair := range strings.Split(val, "&") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				err = fmt.Errorf("config key %q is invalid: %q is not key=value", connection.key("params"), pair)
+				return
+			}
+			connection.Params[kv[0]] = kv[1]
+		}
+	}
+	if val, ok := lookup("replicas"); ok {
+		for _, addr := range strings.Split(val, ",") {
+			addr = strings.TrimSpace(addr)
+			if addr == "" {
+				continue
+			}
+			replica := &Config{Host: addr}
+			if host, port, tmp := net.SplitHostPort(addr); tmp == nil {
+				replica.Host = host
+				if replica.Port, err = strconv.Atoi(port); err != nil {
+					err = fmt.Errorf("config key %q is invalid: %w", connection.key("replicas"), err)
+					return
+				}
+			}
+			connection.Replicas = append(connection.Replicas, replica)
+		}
+	}
+	return
+}
@@ -0,0 +1,196 @@
+package gomysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// ErrShutdown the database connection is shutting down, new statements and transactions are refused
+var ErrShutdown = errors.New("database connection is shutting down")
+
+// gates in-flight work of every database connect object, *sql.DB => *gate
+var gates sync.Map
+
+// gate track the in-flight statements and open transactions of a database connect object
+type gate struct {
+	mutex  sync.Mutex
+	closed bool                           // refuse new statements and transactions
+	serial uint64                         // serial number of the last statement
+	stmts  map[uint64]context.CancelFunc  // in-flight statements outside transactions and transactions being started
+	txs    map[*sql.Tx]context.CancelFunc // open transactions
+	idle   chan struct{}                  // closed when the gate is closed and there is no in-flight work
+}
+
+// gateOf get the gate of database connect object
+func gateOf(database *sql.DB) *gate {
+	if database == nil {
+		return nil
+	}
+	tmp, _ := gates.LoadOrStore(database, &gate{
+		stmts: map[uint64]context.CancelFunc{},
+		txs:   map[*sql.Tx]context.CancelFunc{},
+	})
+	return tmp.(*gate)
+}
+
+// forget drop the gate of database connect object closed or replaced, unless it is still registered under another name, registry must be locked
+func forget(database *sql.DB) {
+	if database == nil {
+		return
+	}
+	for _, tmp := range registry.dbs {
+		if tmp == database {
+			return
+		}
+	}
+	gates.Delete(database)
+}
+
+// enter start a statement outside transaction, call leave when the statement is finished
+func (s *gate) enter(ctx context.Context) (context.Context, func(), error) {
+	if s == nil {
+		return ctx, func() {}, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ctx, nil, ErrShutdown
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.serial++
+	serial := s.serial
+	s.stmts[serial] = cancel
+	leave := func() {
+		s.mutex.Lock()
+		delete(s.stmts, serial)
+		s.wake()
+		s.mutex.Unlock()
+		cancel()
+	}
+	return ctx, leave, nil
+}
+
+// begin get the context used to start a transaction, the transaction is rolled back by database/sql when the context is cancelled
+// the transaction being started is in-flight work until started is called with it, or with nil if starting failed
+func (s *gate) begin(ctx context.Context) (context.Context, func(tx *sql.Tx), error) {
+	if s == nil {
+		return ctx, func(tx *sql.Tx) {}, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ctx, nil, ErrShutdown
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.serial++
+	serial := s.serial
+	s.stmts[serial] = cancel
+	started := func(tx *sql.Tx) {
+		s.mutex.Lock()
+		// the pending begin is removed by abort if the shutdown timed out, the cancelled context rolls the transaction back
+		_, pending := s.stmts[serial]
+		delete(s.stmts, serial)
+		if tx != nil && pending {
+			s.txs[tx] = cancel
+		}
+		s.wake()
+		s.mutex.Unlock()
+		if tx == nil || !pending {
+			cancel()
+		}
+	}
+	return ctx, started, nil
+}
+
+// end the transaction is committed or rolled back
+func (s *gate) end(tx *sql.Tx) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	cancel, ok := s.txs[tx]
+	delete(s.txs, tx)
+	s.wake()
+	s.mutex.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// wake close the idle channel if there is no in-flight work, the mutex must be held
+func (s *gate) wake() {
+	if s.closed && s.idle != nil && len(s.stmts) == 0 && len(s.txs) == 0 {
+		close(s.idle)
+		s.idle = nil
+	}
+}
+
+// close refuse new work, the returned channel is closed when there is no in-flight work
+func (s *gate) close() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	idle := make(chan struct{})
+	s.closed = true
+	s.idle = idle
+	s.wake()
+	return idle
+}
+
+// abort cancel the in-flight statements and roll back the open transactions
+func (s *gate) abort() {
+	s.mutex.Lock()
+	stmts := s.stmts
+	txs := s.txs
+	s.stmts = map[uint64]context.CancelFunc{}
+	s.txs = map[*sql.Tx]context.CancelFunc{}
+	s.mutex.Unlock()
+	for _, cancel := range stmts {
+		cancel()
+	}
+	for tx, cancel := range txs {
+		cancel()
+		_ = tx.Rollback()
+	}
+}
+
+// Shutdown stop accepting new statements and transactions through Hat and Curd on the connection registered under name,
+// wait for the in-flight statements and open transactions to finish, then close the connection and remove it from the registry
+// when ctx is done before that, the in-flight statements are cancelled, the open transactions are rolled back and ctx.Err() is returned after closing
+func Shutdown(ctx context.Context, name string) error {
+	database := GetName(name)
+	if database == nil {
+		return CloseName(name)
+	}
+	g := gateOf(database)
+	var forced error
+	select {
+	case <-g.close():
+	case <-ctx.Done():
+		forced = ctx.Err()
+		g.abort()
+	}
+	if err := CloseName(name); err != nil {
+		return err
+	}
+	return forced
+}
+
+// ShutdownAll shutdown all registered connections at the same time, return the first error
+func ShutdownAll(ctx context.Context) error {
+	names := Names()
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		go func(name string) {
+			errs <- Shutdown(ctx, name)
+		}(name)
+	}
+	var err error
+	for range names {
+		if tmp := <-errs; tmp != nil && err == nil {
+			err = tmp
+		}
+	}
+	return err
+}
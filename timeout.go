@@ -0,0 +1,102 @@
+package gomysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrTimeout the statement did not finish within its timeout, use errors.Is(err, ErrTimeout) to check it
+var ErrTimeout = errors.New("statement timeout")
+
+// TimeoutError the statement did not finish within its timeout
+type TimeoutError struct {
+	Timeout time.Duration // statement timeout, 0 if the deadline comes from the context of the caller
+	Prepare string        // sql statement
+	Err     error         // error returned by the driver
+}
+
+// Error error message
+func (s *TimeoutError) Error() string {
+	if s.Timeout > 0 {
+		return fmt.Sprintf("%s after %s: %v", ErrTimeout.Error(), s.Timeout, s.Err)
+	}
+	return fmt.Sprintf("%s: %v", ErrTimeout.Error(), s.Err)
+}
+
+// Unwrap get the error returned by the driver
+func (s *TimeoutError) Unwrap() error {
+	return s.Err
+}
+
+// Is the error is ErrTimeout
+func (s *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// IsTimeout the error is caused by a statement timeout, either on the client or on the server
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// timeoutError convert the error of a statement that reached its deadline into *TimeoutError
+func timeoutError(ctx context.Context, timeout time.Duration, prepare string, err error) error {
+	if err == nil {
+		return nil
+	}
+	// 3024: ER_QUERY_TIMEOUT, maximum statement execution time exceeded
+	me, ok := err.(*mysql.MySQLError)
+	if !(ok && me.Number == 3024) && !errors.Is(err, context.DeadlineExceeded) && ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	if _, ok = err.(*TimeoutError); ok {
+		return err
+	}
+	return &TimeoutError{
+		Timeout: timeout,
+		Prepare: prepare,
+		Err:     err,
+	}
+}
+
+// MaxExecutionTime add the optimizer hint /*+ MAX_EXECUTION_TIME(n) */ to the SELECT statement, n is the milliseconds of timeout
+// statements that are not SELECT, or already have the hint, are returned unchanged
+func MaxExecutionTime(prepare string, timeout time.Duration) string {
+	if timeout <= 0 || strings.Contains(strings.ToUpper(prepare), "MAX_EXECUTION_TIME") {
+		return prepare
+	}
+	i := 0
+	length := len(prepare)
+	for i < length {
+		switch {
+		case prepare[i] == ' ' || prepare[i] == '\t' || prepare[i] == '\r' || prepare[i] == '\n':
+			i++
+		case strings.HasPrefix(prepare[i:], "/*"):
+			end := strings.Index(prepare[i+2:], "*/")
+			if end < 0 {
+				return prepare
+			}
+			i += end + 4
+		default:
+			keyword := "SELECT"
+			if len(prepare[i:]) < len(keyword) || !strings.EqualFold(prepare[i:i+len(keyword)], keyword) {
+				return prepare
+			}
+			i += len(keyword)
+			// SELECTED, SELECT_xxx is not the keyword
+			if i < length && (prepare[i] == '_' || prepare[i] >= 'a' && prepare[i] <= 'z' || prepare[i] >= 'A' && prepare[i] <= 'Z' || prepare[i] >= '0' && prepare[i] <= '9') {
+				return prepare
+			}
+			ms := timeout.Milliseconds()
+			if ms < 1 {
+				ms = 1
+			}
+			return fmt.Sprintf("%s /*+ MAX_EXECUTION_TIME(%d) */%s", prepare[:i], ms, prepare[i:])
+		}
+	}
+	return prepare
+}
@@ -0,0 +1,466 @@
+package gomysql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XAPrefix prefix of the global transaction identifier generated by XA
+const XAPrefix = "gomysql-"
+
+// xaFormatId format identifier of the xid generated by XA
+const xaFormatId = 1
+
+// xaBranch a branch of XA transaction on a named connection
+type xaBranch struct {
+	name  string    // name of the registered database connection
+	bqual string    // branch qualifier, index/total/server of the branch, server is the fingerprint of the server of the first branch
+	conn  *sql.Conn // the connection the branch is pinned to
+	hat   *Hat      // execute statements of the branch
+	ended bool      // XA END executed
+	done  bool      // XA COMMIT or XA ROLLBACK succeeded, the session has no XA branch and the connection can be reused
+}
+
+// XA distributed transaction across several named connections using two-phase commit
+// the branches are committed in order and rolled back in reverse order, XAResolve relies on it to resolve dangling prepared branches
+type XA struct {
+	gtrid    string      // global transaction identifier
+	branches []*xaBranch // branches in order of names
+	done     bool        // committed or rolled back
+}
+
+// xid format xid of XA statements, the parts are written as hex literals
+func xid(gtrid string, bqual string, formatId int64) string {
+	return fmt.Sprintf("X'%s',X'%s',%d", hex.EncodeToString([]byte(gtrid)), hex.EncodeToString([]byte(bqual)), formatId)
+}
+
+// xaServer get the identity of the server of the connection, several names of the same server have the same identity
+func xaServer(ctx context.Context, conn interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, name string) (server string, err error) {
+	err = conn.QueryRowContext(ctx, "SELECT CONCAT(@@hostname, ':', @@port, ':', @@server_id);").Scan(&server)
+	observe(name, KindQuery, err)
+	return
+}
+
+// xaFingerprint the fingerprint of the server identity written in the branch qualifier
+func xaFingerprint(server string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(server))
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// xaExec execute the XA statement on the connection
+func xaExec(ctx context.Context, conn *sql.Conn, name string, statement string) error {
+	_, err := conn.ExecContext(ctx, statement)
+	observe(name, KindExec, err)
+	return err
+}
+
+// BeginXA start XA transaction on the connections registered under names, one branch for each name
+func BeginXA(ctx context.Context, names ...string) (*XA, error) {
+	if len(names) == 0 {
+		return nil, errors.New("please set the names of database connections first")
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	// the start time in the gtrid lets XAResolve leave the transactions of live coordinators alone
+	xa := &XA{
+		gtrid: fmt.Sprintf("%s%016x%s", XAPrefix, time.Now().UnixNano(), hex.EncodeToString(random)),
+	}
+	server := ""
+	for key, name := range names {
+		hat := Db2(name)
+		if hat.db == nil {
+			_ = xa.Rollback(ctx)
+			return nil, hat.unregistered()
+		}
+		conn, err := hat.db.Conn(ctx)
+		if err != nil {
+			_ = xa.Rollback(ctx)
+			return nil, err
+		}
+		hat.session.conn = conn
+		// XAResolve presumes the transaction committing only if it can scan the server of the first branch
+		if key == 0 {
+			if server, err = xaServer(ctx, conn, name); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			server = xaFingerprint(server)
+		}
+		branch := &xaBranch{
+			name:  name,
+			bqual: fmt.Sprintf("%d/%d/%s", key, len(names), server),
+			conn:  conn,
+			hat:   hat,
+		}
+		if err = xaExec(ctx, conn, name, "XA START "+xid(xa.gtrid, branch.bqual, xaFormatId)); err != nil {
+			discard(conn)
+			_ = xa.Rollback(ctx)
+			return nil, err
+		}
+		xa.branches = append(xa.branches, branch)
+	}
+	return xa, nil
+}
+
+// Xid get the global transaction identifier
+func (s *XA) Xid() string {
+	return s.gtrid
+}
+
+// Hat get the hat executing statements in the branch of the connection registered under name, nil if there is no such branch
+func (s *XA) Hat(name string) *Hat {
+	for _, branch := range s.branches {
+		if branch.name == name {
+			return branch.hat
+		}
+	}
+	return nil
+}
+
+// Curd get the curd executing statements in the branch of the connection registered under name, nil if there is no such branch
+func (s *XA) Curd(name string) *Curd {
+	hat := s.Hat(name)
+	if hat == nil {
+		return nil
+	}
+	return NewCurd(hat)
+}
+
+// end execute XA END on all branches that are not ended yet
+func (s *XA) end(ctx context.Context) error {
+	for _, branch := range s.branches {
+		if branch.ended {
+			continue
+		}
+		if err := xaExec(ctx, branch.conn, branch.name, "XA END "+xid(s.gtrid, branch.bqual, xaFormatId)); err != nil {
+			return err
+		}
+		branch.ended = true
+	}
+	return nil
+}
+
+// close release the pinned connections, the connections of the branches not committed or rolled back are discarded
+// because their sessions still hold the XA branches, the server rolls back the branches not prepared yet when the connection is closed
+func (s *XA) close() {
+	s.done = true
+	for _, branch := range s.branches {
+		if branch.done {
+			_ = branch.conn.Close()
+		} else {
+			discard(branch.conn)
+		}
+	}
+}
+
+// Commit prepare all branches, then commit them in order, all branches are rolled back if any of them fails to prepare
+// when committing fails after all branches are prepared, the remaining prepared branches are resolved by XAResolve
+func (s *XA) Commit(ctx context.Context) (err error) {
+	if s.done {
+		return errors.New("the XA transaction is already committed or rolled back")
+	}
+	if err = s.end(ctx); err != nil {
+		return s.abort(ctx, err)
+	}
+	if len(s.branches) == 1 {
+		branch := s.branches[0]
+		err = xaExec(ctx, branch.conn, branch.name, "XA COMMIT "+xid(s.gtrid, branch.bqual, xaFormatId)+" ONE PHASE")
+		observe(branch.name, KindCommit, err)
+		branch.done = err == nil
+		s.close()
+		return
+	}
+	for _, branch := range s.branches {
+		if err = xaExec(ctx, branch.conn, branch.name, "XA PREPARE "+xid(s.gtrid, branch.bqual, xaFormatId)); err != nil {
+			return s.abort(ctx, err)
+		}
+	}
+	defer s.close()
+	for _, branch := range s.branches {
+		err = xaExec(ctx, branch.conn, branch.name, "XA COMMIT "+xid(s.gtrid, branch.bqual, xaFormatId))
+		observe(branch.name, KindCommit, err)
+		branch.done = err == nil
+		if err != nil {
+			return fmt.Errorf("commit XA transaction %s on %q: %w", s.gtrid, branch.name, err)
+		}
+	}
+	return
+}
+
+// abort roll back after err, return err and the error of rolling back
+func (s *XA) abort(ctx context.Context, err error) error {
+	if tmp := s.Rollback(ctx); tmp != nil {
+		return &TxError{Err: err, RollbackErr: tmp}
+	}
+	return err
+}
+
+// Rollback roll back all branches in reverse order, it stops at the first branch failing to roll back
+// so the first branch is left prepared if any branch is, and XAResolve rolls the transaction back instead of presuming it committing
+func (s *XA) Rollback(ctx context.Context) (err error) {
+	if s.done {
+		return nil
+	}
+	defer s.close()
+	// the branches that fail XA END are still rolled back, the server rolls them back when the connection is closed anyway
+	_ = s.end(ctx)
+	for i := len(s.branches) - 1; i >= 0; i-- {
+		branch := s.branches[i]
+		err = xaExec(ctx, branch.conn, branch.name, "XA ROLLBACK "+xid(s.gtrid, branch.bqual, xaFormatId))
+		observe(branch.name, KindRollback, err)
+		if err != nil {
+			return
+		}
+		branch.done = true
+	}
+	return
+}
+
+// XATransaction closure execute XA transaction across the connections registered under names
+// automatic rollback if closure returns an error or panics, the panic is raised again after rolling back
+func XATransaction(ctx context.Context, names []string, closure func(xa *XA) (err error)) (err error) {
+	var xa *XA
+	xa, err = BeginXA(ctx, names...)
+	if err != nil {
+		return
+	}
+	panicked := true
+	defer func() {
+		if panicked {
+			_ = xa.Rollback(ctx)
+		}
+	}()
+	err = closure(xa)
+	panicked = false
+	if err != nil {
+		return xa.abort(ctx, err)
+	}
+	return xa.Commit(ctx)
+}
+
+// XAPrepared a prepared XA branch listed by XA RECOVER
+type XAPrepared struct {
+	Name     string // name of the connection it was found on
+	FormatId int64  // format identifier
+	Gtrid    string // global transaction identifier
+	Bqual    string // branch qualifier
+}
+
+// XARecover list the prepared XA branches on the connection registered under name
+func XARecover(ctx context.Context, name string) (prepared []*XAPrepared, err error) {
+	database := GetName(name)
+	if database == nil {
+		err = Db2(name).unregistered()
+		return
+	}
+	// XA statements can not be prepared, execute it as plain text
+	var rows *sql.Rows
+	rows, err = database.QueryContext(ctx, "XA RECOVER")
+	observe(name, KindQuery, err)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var formatId, gtridLength, bqualLength int64
+		var data []byte
+		if err = rows.Scan(&formatId, &gtridLength, &bqualLength, &data); err != nil {
+			return
+		}
+		if gtridLength < 0 || bqualLength < 0 || gtridLength+bqualLength > int64(len(data)) {
+			err = fmt.Errorf("invalid XA RECOVER data length %d", len(data))
+			return
+		}
+		prepared = append(prepared, &XAPrepared{
+			Name:     name,
+			FormatId: formatId,
+			Gtrid:    string(data[:gtridLength]),
+			Bqual:    string(data[gtridLength : gtridLength+bqualLength]),
+		})
+	}
+	err = rows.Err()
+	return
+}
+
+// XADecision how to resolve a prepared XA branch
+type XADecision int
+
+const (
+	XASkip     XADecision = iota // leave the branch prepared
+	XACommit                     // commit the branch
+	XARollback                   // roll back the branch
+)
+
+// xaBqual parse the branch index, the number of branches and the server fingerprint of the first branch from the branch qualifier generated by XA
+func xaBqual(bqual string) (index int, total int, server string, ok bool) {
+	parts := strings.Split(bqual, "/")
+	if len(parts) != 3 || len(parts[2]) != 16 {
+		return
+	}
+	server = parts[2]
+	var err error
+	if index, err = strconv.Atoi(parts[0]); err != nil {
+		return
+	}
+	if total, err = strconv.Atoi(parts[1]); err != nil {
+		return
+	}
+	ok = index >= 0 && index < total
+	return
+}
+
+// xaStarted parse the start time from the gtrid generated by XA
+func xaStarted(gtrid string) (time.Time, bool) {
+	tmp := strings.TrimPrefix(gtrid, XAPrefix)
+	if len(tmp) == len(gtrid) || len(tmp) < 16 {
+		return time.Time{}, false
+	}
+	nano, err := strconv.ParseInt(tmp[:16], 16, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nano), true
+}
+
+// XAResolver options of XAResolve
+// resolving is only safe when no coordinator is running the transactions, a live coordinator may be between XA PREPARE and XA COMMIT,
+// use MinAge or Exclude to leave the transactions of live coordinators alone
+type XAResolver struct {
+	Decide  func(prepared *XAPrepared, siblings []*XAPrepared) XADecision // how to resolve the branch, nil resolves only the branches generated by XA
+	MinAge  time.Duration                                                 // skip the transactions started less than MinAge ago, or whose start time is unknown
+	Exclude func(gtrid string) bool                                       // skip the transactions still run by a live coordinator
+}
+
+// skip the transaction must be left alone
+func (s *XAResolver) skip(gtrid string, now time.Time) bool {
+	if s.Exclude != nil && s.Exclude(gtrid) {
+		return true
+	}
+	if s.MinAge > 0 {
+		started, ok := xaStarted(gtrid)
+		return !ok || now.Sub(started) < s.MinAge
+	}
+	return false
+}
+
+// XAResolve resolve the dangling prepared XA branches found on the connections registered under names, nil resolver uses the default options
+// the default decision resolves only the branches generated by XA:
+// if the first branch of the transaction is still prepared, the transaction never started committing and its branches are rolled back,
+// otherwise committing has started, the remaining branches are committed if they are the last ones of the transaction and the server of the first branch was scanned,
+// the transactions that can not be accounted for are skipped
+// names should include every connection the XA transactions span, the resolved branches are returned
+func XAResolve(ctx context.Context, resolver *XAResolver, names ...string) (resolved []*XAPrepared, err error) {
+	if resolver == nil {
+		resolver = &XAResolver{}
+	}
+	// the fingerprints of the servers scanned
+	scanned := map[string]bool{}
+	decide := resolver.Decide
+	if decide == nil {
+		decide = func(prepared *XAPrepared, siblings []*XAPrepared) XADecision {
+			return xaPresumed(prepared, siblings, scanned)
+		}
+	}
+	var all []*XAPrepared
+	// several names may point to the same server
+	seen := map[string]bool{}
+	for _, name := range names {
+		database := GetName(name)
+		if database == nil {
+			err = Db2(name).unregistered()
+			return
+		}
+		var server string
+		if server, err = xaServer(ctx, database, name); err != nil {
+			return
+		}
+		if seen[server] {
+			continue
+		}
+		seen[server] = true
+		var tmp []*XAPrepared
+		tmp, err = XARecover(ctx, name)
+		if err != nil {
+			return
+		}
+		all = append(all, tmp...)
+		scanned[xaFingerprint(server)] = true
+	}
+	groups := map[string][]*XAPrepared{}
+	for _, prepared := range all {
+		groups[prepared.Gtrid] = append(groups[prepared.Gtrid], prepared)
+	}
+	now := time.Now()
+	for _, prepared := range all {
+		if resolver.skip(prepared.Gtrid, now) {
+			continue
+		}
+		statement := ""
+		switch decide(prepared, groups[prepared.Gtrid]) {
+		case XACommit:
+			statement = "XA COMMIT "
+		case XARollback:
+			statement = "XA ROLLBACK "
+		default:
+			continue
+		}
+		_, err = GetName(prepared.Name).ExecContext(ctx, statement+xid(prepared.Gtrid, prepared.Bqual, prepared.FormatId))
+		observe(prepared.Name, KindExec, err)
+		if err != nil {
+			return
+		}
+		resolved = append(resolved, prepared)
+	}
+	return
+}
+
+// xaPresumed the default decision of XAResolve, scanned is the fingerprints of the servers scanned
+func xaPresumed(prepared *XAPrepared, siblings []*XAPrepared, scanned map[string]bool) XADecision {
+	if prepared.FormatId != xaFormatId || !strings.HasPrefix(prepared.Gtrid, XAPrefix) {
+		return XASkip
+	}
+	_, total, server, ok := xaBqual(prepared.Bqual)
+	if !ok {
+		return XASkip
+	}
+	indexes := map[int]bool{}
+	first := total
+	for _, sibling := range siblings {
+		index, count, tmp, ok := xaBqual(sibling.Bqual)
+		if !ok || count != total || tmp != server {
+			return XASkip
+		}
+		indexes[index] = true
+		if index < first {
+			first = index
+		}
+	}
+	// committing never started, rolling back is safe even if some branches were not scanned
+	if indexes[0] {
+		return XARollback
+	}
+	// branch 0 may be still prepared on a server that was not scanned
+	if !scanned[server] {
+		return XASkip
+	}
+	// the branches are committed in order, the branches still prepared must be the last ones
+	for index := first; index < total; index++ {
+		if !indexes[index] {
+			return XASkip
+		}
+	}
+	return XACommit
+}
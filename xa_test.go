@@ -0,0 +1,140 @@
+package gomysql
+
+import (
+	"testing"
+)
+
+func TestXABqual(t *testing.T) {
+	server := xaFingerprint("db1:3306:1")
+	tests := []struct {
+		bqual  string
+		index  int
+		total  int
+		server string
+		ok     bool
+	}{
+		{bqual: "0/2/" + server, index: 0, total: 2, server: server, ok: true},
+		{bqual: "1/2/" + server, index: 1, total: 2, server: server, ok: true},
+		{bqual: "2/2/" + server, index: 2, total: 2, server: server},
+		{bqual: "-1/2/" + server, index: -1, total: 2, server: server},
+		{bqual: "0/2"},
+		{bqual: "0/2/abc", index: 0},
+		{bqual: "a/2/" + server, server: server},
+		{bqual: "0/b/" + server, server: server},
+		{bqual: "0/2/" + server + "/x"},
+		{bqual: ""},
+	}
+	for _, test := range tests {
+		index, total, server, ok := xaBqual(test.bqual)
+		if ok != test.ok || ok && (index != test.index || total != test.total || server != test.server) {
+			t.Errorf("xaBqual(%q) = %d, %d, %q, %v, want %d, %d, %q, %v", test.bqual, index, total, server, ok, test.index, test.total, test.server, test.ok)
+		}
+	}
+}
+
+func TestXAPresumed(t *testing.T) {
+	server := xaFingerprint("db1:3306:1")
+	other := xaFingerprint("db2:3306:2")
+	gtrid := XAPrefix + "0000000000000001" + "00112233445566778899aabbccddeeff"
+	branch := func(bqual string) *XAPrepared {
+		return &XAPrepared{FormatId: xaFormatId, Gtrid: gtrid, Bqual: bqual}
+	}
+	scanned := map[string]bool{server: true, other: true}
+	tests := []struct {
+		name     string
+		prepared *XAPrepared
+		siblings []*XAPrepared
+		scanned  map[string]bool
+		want     XADecision
+	}{
+		{
+			name:     "first branch prepared",
+			prepared: branch("1/3/" + server),
+			siblings: []*XAPrepared{branch("0/3/" + server), branch("1/3/" + server), branch("2/3/" + server)},
+			scanned:  scanned,
+			want:     XARollback,
+		},
+		{
+			name:     "first branch prepared on a server not scanned otherwise",
+			prepared: branch("0/3/" + other),
+			siblings: []*XAPrepared{branch("0/3/" + other)},
+			scanned:  map[string]bool{},
+			want:     XARollback,
+		},
+		{
+			name:     "last branches prepared",
+			prepared: branch("1/3/" + server),
+			siblings: []*XAPrepared{branch("1/3/" + server), branch("2/3/" + server)},
+			scanned:  scanned,
+			want:     XACommit,
+		},
+		{
+			name:     "last branch prepared",
+			prepared: branch("2/3/" + server),
+			siblings: []*XAPrepared{branch("2/3/" + server)},
+			scanned:  scanned,
+			want:     XACommit,
+		},
+		{
+			name:     "server of the first branch not scanned",
+			prepared: branch("1/2/" + other),
+			siblings: []*XAPrepared{branch("1/2/" + other)},
+			scanned:  map[string]bool{server: true},
+			want:     XASkip,
+		},
+		{
+			name:     "branches prepared are not the last ones",
+			prepared: branch("1/3/" + server),
+			siblings: []*XAPrepared{branch("1/3/" + server)},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+		{
+			name:     "branches prepared with a gap",
+			prepared: branch("1/4/" + server),
+			siblings: []*XAPrepared{branch("1/4/" + server), branch("3/4/" + server)},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+		{
+			name:     "siblings disagree on the number of branches",
+			prepared: branch("1/3/" + server),
+			siblings: []*XAPrepared{branch("1/3/" + server), branch("2/4/" + server)},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+		{
+			name:     "siblings disagree on the server of the first branch",
+			prepared: branch("1/3/" + server),
+			siblings: []*XAPrepared{branch("1/3/" + server), branch("2/3/" + other)},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+		{
+			name:     "branch qualifier of the old format",
+			prepared: branch("1/2"),
+			siblings: []*XAPrepared{branch("1/2")},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+		{
+			name:     "format identifier of others",
+			prepared: &XAPrepared{FormatId: 2, Gtrid: gtrid, Bqual: "1/2/" + server},
+			siblings: []*XAPrepared{{FormatId: 2, Gtrid: gtrid, Bqual: "1/2/" + server}},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+		{
+			name:     "gtrid of others",
+			prepared: &XAPrepared{FormatId: xaFormatId, Gtrid: "other", Bqual: "0/2/" + server},
+			siblings: []*XAPrepared{{FormatId: xaFormatId, Gtrid: "other", Bqual: "0/2/" + server}},
+			scanned:  scanned,
+			want:     XASkip,
+		},
+	}
+	for _, test := range tests {
+		if got := xaPresumed(test.prepared, test.siblings, test.scanned); got != test.want {
+			t.Errorf("%s: xaPresumed = %d, want %d", test.name, got, test.want)
+		}
+	}
+}